@@ -0,0 +1,39 @@
+package main
+
+// expandEngines copies every target once per distinct CSE ID in the pool,
+// each pinned to its engine, for -all-cse.
+func expandEngines(targets []Target, pool *KeyPool) []Target {
+	engines := distinctEngines(pool)
+	expanded := make([]Target, 0, len(targets)*len(engines))
+	for _, target := range targets {
+		for _, engine := range engines {
+			pinned := target
+			pinned.CSEID = engine.cseID
+			expanded = append(expanded, pinned)
+		}
+	}
+	return expanded
+}
+
+// dedupeDomainURLs drops results a domain already got from another engine
+// or date window, keeping the first, and corrects each domain's count to
+// match.
+func dedupeDomainURLs(results []Result, searches map[string]SearchResult) []Result {
+	seen := make(map[string]bool)
+	counts := make(map[string]int)
+	var kept []Result
+	for _, result := range results {
+		key := result.Domain + "\x00" + result.URL
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		counts[result.Domain]++
+		kept = append(kept, result)
+	}
+	for domain, search := range searches {
+		search.Count = counts[domain]
+		searches[domain] = search
+	}
+	return kept
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAllCSE(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, log := newFakeCSE(t, 3)
+	svc := pool.keys[0].svc
+	pool.keys = append(pool.keys, &APIKey{svc: svc, cseID: "cx2", name: "second", health: 1})
+
+	targets := expandEngines([]Target{{Domain: "example.com", Query: "inurl:admin"}}, pool)
+	if len(targets) != 2 || targets[0].CSEID != "cx" || targets[1].CSEID != "cx2" {
+		t.Fatalf("targets = %+v, want one per engine", targets)
+	}
+
+	searches := processDomains(context.Background(), targets, pool)
+	var engines []string
+	for _, request := range log.all() {
+		engines = append(engines, request.Get("cx"))
+	}
+	sort.Strings(engines)
+	if strings.Join(engines, ",") != "cx,cx2" {
+		t.Errorf("queried engines %v, want cx and cx2", engines)
+	}
+
+	found := dedupeDomainURLs(collectedResults(), searches)
+	if len(found) != 3 || searches["example.com"].Count != 3 {
+		t.Errorf("%d results, count %d; want the 3 distinct URLs", len(found), searches["example.com"].Count)
+	}
+}
+
+func TestAcquireEngine(t *testing.T) {
+	setupLogger()
+	pool := newTestPool(4, 1, 1)
+	pool.keys[0].cseID, pool.keys[1].cseID = "cx-a", "cx-b"
+	// Each key's share of the 4 slots is 2.
+	for i := 0; i < 2; i++ {
+		key, err := pool.AcquireEngine(context.Background(), 0, "cx-b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.cseID != "cx-b" {
+			t.Errorf("got key for %s, want cx-b", key.cseID)
+		}
+	}
+}
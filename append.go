@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// mergeExistingSubdomains reads the subdomain output already at path in the
+// given format and returns results with those subdomains merged in. Every
+// list in the union is re-sorted, so the rewritten file stays globally sorted
+// rather than having each run's entries tacked onto the end.
+func mergeExistingSubdomains(path, format string, results map[string]SearchResult) (map[string]SearchResult, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var existing map[string][]string
+	switch format {
+	case "json":
+		existing, err = parseSubdomainsJSON(data)
+	case "csv":
+		existing, err = parseSubdomainsCSV(data)
+	default:
+		existing, err = parseSubdomainsTXT(data, results)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing %s: %v", path, err)
+	}
+
+	merged := make(map[string]SearchResult, len(results)+len(existing))
+	for domain, result := range results {
+		merged[domain] = result
+	}
+	for domain, subs := range existing {
+		result := merged[domain]
+		result.Domain = domain
+		result.Subdomains = unionSorted(result.Subdomains, subs)
+		merged[domain] = result
+	}
+	return merged, nil
+}
+
+func unionSorted(a, b []string) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for _, item := range append(append([]string{}, a...), b...) {
+		set[item] = true
+	}
+	union := make([]string, 0, len(set))
+	for item := range set {
+		union = append(union, item)
+	}
+	sort.Strings(union)
+	return union
+}
+
+// parseSubdomainsJSON accepts both the plain domain map and -detailed-json.
+func parseSubdomainsJSON(data []byte) (map[string][]string, error) {
+	var versioned struct {
+		SchemaVersion int             `json:"schema_version"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &versioned); err == nil && versioned.SchemaVersion >= 2 {
+		data = versioned.Data
+	}
+
+	var subs map[string][]string
+	if err := json.Unmarshal(data, &subs); err == nil {
+		return subs, nil
+	}
+
+	var detailed []SearchResult
+	if err := json.Unmarshal(data, &detailed); err != nil {
+		return nil, err
+	}
+	subs = make(map[string][]string, len(detailed))
+	for _, result := range detailed {
+		subs[result.Domain] = append(subs[result.Domain], result.Subdomains...)
+	}
+	return subs, nil
+}
+
+func parseSubdomainsCSV(data []byte) (map[string][]string, error) {
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make(map[string][]string)
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "Domain" {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected Domain,Subdomain", i+1)
+		}
+		subs[record[0]] = append(subs[record[0]], record[1])
+	}
+	return subs, nil
+}
+
+// parseSubdomainsTXT reads text output. Files for several domains have a
+// "domain:" header before each group; a single-domain file has none, so its
+// lines are matched to a target by suffix.
+func parseSubdomainsTXT(data []byte, results map[string]SearchResult) (map[string][]string, error) {
+	subs := make(map[string][]string)
+	current := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		indented := strings.HasPrefix(line, " ")
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || indented:
+			// Indented lines are -probe-meta details, not subdomains.
+			continue
+		case strings.HasSuffix(line, ":"):
+			current = strings.TrimSuffix(line, ":")
+			continue
+		}
+
+		domain := current
+		if domain == "" {
+			domain = owningTarget(line, results)
+		}
+		if domain == "" {
+			return nil, fmt.Errorf("line %d: cannot tell which target %q belongs to", i+1, line)
+		}
+		subs[domain] = append(subs[domain], line)
+	}
+	return subs, nil
+}
+
+func owningTarget(host string, results map[string]SearchResult) string {
+	for domain := range results {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain
+		}
+	}
+	if len(results) == 1 {
+		for domain := range results {
+			return domain
+		}
+	}
+	return ""
+}
+
+// appendCSVFile appends CSV rows to path. If the file already has content,
+// the header row of data is dropped so the file keeps exactly one header,
+// after checking that it matches the existing one.
+func appendCSVFile(path string, data []byte) error {
+	header, rows, _ := strings.Cut(string(data), "\n")
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(existing) > 0 {
+		existingHeader, _, _ := strings.Cut(string(existing), "\n")
+		if strings.TrimSuffix(existingHeader, "\r") != header {
+			return fmt.Errorf("cannot append to %s: its CSV header %q does not match %q", path, existingHeader, header)
+		}
+		data = []byte(rows)
+		if existing[len(existing)-1] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	file, err := openOutput(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendKeepsOutputSorted(t *testing.T) {
+	setupLogger()
+	*subdomains = true
+	defer func() { *outputArg, *formatArg, *appendOutput, *subdomains = "", "txt", false, false }()
+
+	runs := []map[string]SearchResult{
+		{
+			"example.com": {Domain: "example.com", Subdomains: []string{"m.example.com", "www.example.com"}},
+		},
+		{
+			"example.com": {Domain: "example.com", Subdomains: []string{"api.example.com", "www.example.com"}},
+			"example.org": {Domain: "example.org", Subdomains: []string{"b.example.org"}},
+		},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"txt", "example.com:\napi.example.com\nm.example.com\nwww.example.com\n\nexample.org:\nb.example.org\n\n"},
+		{"csv", "Domain,Subdomain\nexample.com,api.example.com\nexample.com,m.example.com\nexample.com,www.example.com\nexample.org,b.example.org\n"},
+		{"json", "{\n  \"example.com\": [\n    \"api.example.com\",\n    \"m.example.com\",\n    \"www.example.com\"\n  ],\n  \"example.org\": [\n    \"b.example.org\"\n  ]\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := outputTarget{format: tt.format, path: filepath.Join(t.TempDir(), "subs."+tt.format)}
+			*appendOutput = true
+			for _, run := range runs {
+				if err := outputSubdomains(run, out); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := os.ReadFile(out.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendCSVResults(t *testing.T) {
+	setupLogger()
+	out := outputTarget{format: "csv", path: filepath.Join(t.TempDir(), "results.csv")}
+	*appendOutput = true
+	defer func() { *appendOutput = false }()
+
+	for _, url := range []string{"https://a.example.com/1", "https://b.example.com/2"} {
+		if err := outputResultsCSV([]Result{{Domain: "example.com", URL: url}}, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(out.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Domain,Host,Subdomains,Title,URL,Snippet,CollectedAt\n" +
+		"example.com,,,,https://a.example.com/1,,\n" +
+		"example.com,,,,https://b.example.com/2,,\n"
+	if string(got) != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+
+	*urlsOnly = true
+	defer func() { *urlsOnly = false }()
+	if err := outputURLs([]Result{{Domain: "example.com", URL: "https://c.example.com/"}}, out); err == nil {
+		t.Error("appending rows with a different header succeeded")
+	}
+}
+
+func TestParseSubdomainsTXTUnknownTarget(t *testing.T) {
+	results := map[string]SearchResult{"example.com": {}, "example.org": {}}
+	if _, err := parseSubdomainsTXT([]byte("a.example.net\n"), results); err == nil {
+		t.Error("expected an error for a line matching no target")
+	}
+}
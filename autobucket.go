@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// registrableDomain returns the eTLD+1 that -auto-bucket files a result
+// under, e.g. example.co.uk for a.b.example.co.uk. IP addresses and hosts
+// without a known public suffix are their own bucket.
+func registrableDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return registrable
+}
+
+// bucketResults splits the single unscoped -auto-bucket search, stored under
+// the empty domain, into one entry per registrable domain. found already has
+// each result's bucket as its Domain and gives the per-domain counts.
+func bucketResults(results map[string]SearchResult, found []Result) map[string]SearchResult {
+	combined, ok := results[""]
+	if !ok {
+		return results
+	}
+
+	buckets := make(map[string]SearchResult, len(results))
+	for domain, result := range results {
+		if domain != "" {
+			buckets[domain] = result
+		}
+	}
+	bucket := func(domain string) SearchResult {
+		result, ok := buckets[domain]
+		if !ok {
+			result = SearchResult{
+				Domain:    domain,
+				Queries:   combined.Queries,
+				Truncated: combined.Truncated,
+				Partial:   combined.Partial,
+			}
+		}
+		return result
+	}
+	for _, result := range found {
+		if result.Domain == "" {
+			continue
+		}
+		group := bucket(result.Domain)
+		group.Count++
+		buckets[result.Domain] = group
+	}
+	for _, sub := range combined.Subdomains {
+		domain := registrableDomain(sub)
+		group := bucket(domain)
+		group.Subdomains = append(group.Subdomains, sub)
+		buckets[domain] = group
+	}
+	return buckets
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"a.b.example.co.uk": "example.co.uk",
+		"WWW.Example.com.":  "example.com",
+		"example.com":       "example.com",
+		"10.0.0.1":          "10.0.0.1",
+		"localhost":         "localhost",
+	}
+	for host, want := range tests {
+		if got := registrableDomain(host); got != want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestBucketResults(t *testing.T) {
+	results := map[string]SearchResult{"": {
+		Queries:    []string{"ext:env"},
+		Subdomains: []string{"api.a.com", "dev.b.co.uk", "www.a.com"},
+		Count:      3,
+		Truncated:  true,
+	}}
+	found := []Result{{Domain: "a.com"}, {Domain: "a.com"}, {Domain: "b.co.uk"}}
+
+	got := bucketResults(results, found)
+	want := map[string]SearchResult{
+		"a.com":   {Domain: "a.com", Queries: []string{"ext:env"}, Subdomains: []string{"api.a.com", "www.a.com"}, Count: 2, Truncated: true},
+		"b.co.uk": {Domain: "b.co.uk", Queries: []string{"ext:env"}, Subdomains: []string{"dev.b.co.uk"}, Count: 1, Truncated: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketResults = %+v, want %+v", got, want)
+	}
+}
+
+func TestAutoBucketSearchesWithoutSite(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*autoBucket = true
+	defer func() { *autoBucket = false }()
+
+	pool, log := newFakeCSE(t, 3)
+	searches := processDomains(context.Background(), []Target{{Query: "ext:env"}}, pool)
+
+	if q := log.all()[0].Get("q"); q != "ext:env" {
+		t.Errorf("q = %q, want the raw query without site:", q)
+	}
+	found := collectedResults()
+	for _, result := range found {
+		if result.Domain != "example.com" {
+			t.Errorf("result %s bucketed under %q, want example.com", result.URL, result.Domain)
+		}
+	}
+	buckets := bucketResults(searches, found)
+	if len(buckets) != 1 || buckets["example.com"].Count != 3 || len(buckets["example.com"].Subdomains) != 3 {
+		t.Errorf("buckets = %+v, want example.com with 3 results and 3 subdomains", buckets)
+	}
+}
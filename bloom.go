@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	bloomMagic     = "GDBF"
+	bloomHeaderLen = len(bloomMagic) + 8 + 4
+)
+
+// BloomFilter is a fixed-size probabilistic set. Test never reports a false
+// negative, but may report an item as present that was never added.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint32
+}
+
+func NewBloomFilter(size uint64, hashes uint32) *BloomFilter {
+	if size < 64 {
+		size = 64
+	}
+	if hashes == 0 {
+		hashes = 1
+	}
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// locations uses double hashing over the two halves of a 128-bit FNV-1a
+// digest to derive the bit positions for an item.
+func (b *BloomFilter) locations(item string) []uint64 {
+	h := fnv.New128a()
+	h.Write([]byte(item))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:])
+
+	locs := make([]uint64, b.hashes)
+	for i := range locs {
+		locs[i] = (h1 + uint64(i)*h2) % b.size
+	}
+	return locs
+}
+
+func (b *BloomFilter) Add(item string) {
+	for _, loc := range b.locations(item) {
+		b.bits[loc/64] |= 1 << (loc % 64)
+	}
+}
+
+func (b *BloomFilter) Test(item string) bool {
+	for _, loc := range b.locations(item) {
+		if b.bits[loc/64]&(1<<(loc%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadBloomFilter reads a filter saved by Save, or returns an empty filter
+// with the given dimensions if the file does not exist yet. A saved filter
+// keeps its original dimensions since its bits cannot be rehashed.
+func LoadBloomFilter(path string, size uint64, hashes uint32) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewBloomFilter(size, hashes), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(file)
+	magic := make([]byte, len(bloomMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != bloomMagic {
+		return nil, fmt.Errorf("%s is not a bloom filter file", path)
+	}
+
+	var header struct {
+		Size   uint64
+		Hashes uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter header: %v", err)
+	}
+
+	// Check the header against the file length before allocating, so a
+	// corrupt size cannot trigger a huge allocation.
+	if header.Size < 64 || header.Hashes == 0 || header.Size > uint64(info.Size())*8 ||
+		uint64(bloomHeaderLen)+8*((header.Size+63)/64) != uint64(info.Size()) {
+		return nil, fmt.Errorf("%s is corrupt: header does not match file size", path)
+	}
+
+	filter := NewBloomFilter(header.Size, header.Hashes)
+	if err := binary.Read(r, binary.LittleEndian, filter.bits); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter: %v", err)
+	}
+	return filter, nil
+}
+
+// Save writes the filter to a temporary file next to path and renames it
+// into place, so a failed write never destroys the previous history.
+func (b *BloomFilter) Save(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	w := bufio.NewWriter(file)
+	w.WriteString(bloomMagic)
+	binary.Write(w, binary.LittleEndian, b.size)
+	binary.Write(w, binary.LittleEndian, b.hashes)
+	binary.Write(w, binary.LittleEndian, b.bits)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
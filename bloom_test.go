@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+
+	filter, err := LoadBloomFilter(path, 1<<12, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.Add("sub:api.example.com")
+	if err := filter.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBloomFilter(path, 64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.size != 1<<12 || loaded.hashes != 5 {
+		t.Errorf("dimensions = %d/%d, want the saved 4096/5", loaded.size, loaded.hashes)
+	}
+	if !loaded.Test("sub:api.example.com") {
+		t.Error("saved item not found after reload")
+	}
+	if loaded.Test("sub:www.example.com") {
+		t.Error("unsaved item reported as seen")
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLoadBloomFilterRejectsCorruptHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+	if err := NewBloomFilter(1<<12, 5).Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint64(data[len(bloomMagic):], 1<<62)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadBloomFilter(path, 64, 1); err == nil {
+		t.Error("corrupt header accepted")
+	}
+}
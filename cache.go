@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/customsearch/v1"
+)
+
+// responseCache stores API responses on disk so repeated runs within ttl do
+// not spend quota on pages they have already fetched. A nil cache is valid
+// and never hits.
+type responseCache struct {
+	dir     string
+	ttl     time.Duration
+	engines string
+}
+
+// searchCache is the cache used by performSearch, set up from -cache-dir.
+var searchCache *responseCache
+
+// newResponseCache keys entries on the pool's CSE IDs as well as the
+// request, so changing the configured engines does not serve stale pages.
+func newResponseCache(dir string, ttl time.Duration, pool *KeyPool) *responseCache {
+	var ids []string
+	for _, engine := range distinctEngines(pool) {
+		ids = append(ids, engine.cseID)
+	}
+	sort.Strings(ids)
+	return &responseCache{dir: dir, ttl: ttl, engines: strings.Join(ids, ",")}
+}
+
+// cacheQuery is the query a target's pages are cached under. A search
+// pinned to one engine by -all-cse, or to a -deep-paginate date window, is
+// cached apart from the others.
+func cacheQuery(target Target, query string) string {
+	if target.DateRange != "" {
+		query = "date:" + target.DateRange + "\x00" + query
+	}
+	if target.CSEID == "" {
+		return query
+	}
+	return "cx:" + target.CSEID + "\x00" + query
+}
+
+func (c *responseCache) path(query string, start, num int64) string {
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%s\x00%t\x00%s\x00%s\x00%t\x00%t\x00%s", c.engines, query, start, num, *geoArg, *includeOmitted, *fileTypeParam, activeLocale.lr, *imagesMode, *c2coff, extraParams.String())
+	// Options added later are only part of the key when set, so existing
+	// cache entries stay valid.
+	if *siteSearch != "" {
+		key += fmt.Sprintf("\x00site:%s\x00%s", *siteSearch, *siteFilter)
+	}
+	if *linksTo != "" {
+		key += "\x00link:" + *linksTo
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+// lookup returns the cached page and its age without checking the TTL.
+func (c *responseCache) lookup(query string, start, num int64) (*customsearch.Search, time.Duration, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	path := c.path(query, start, num)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	var resp customsearch.Search
+	if err := json.Unmarshal(data, &resp); err != nil {
+		logger.Debug("Ignoring unreadable cache entry %s: %v", path, err)
+		return nil, 0, false
+	}
+	return &resp, time.Since(info.ModTime()), true
+}
+
+// get returns a cached page that is younger than the TTL.
+func (c *responseCache) get(query string, start, num int64) (*customsearch.Search, bool) {
+	resp, age, ok := c.lookup(query, start, num)
+	if !ok || age > c.ttl {
+		return nil, false
+	}
+	return resp, true
+}
+
+func (c *responseCache) put(query string, start, num int64, resp *customsearch.Search) {
+	if c == nil {
+		return
+	}
+	path := c.path(query, start, num)
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), outputDirMode())
+	}
+	if err == nil {
+		err = writeOutput(path, data)
+	}
+	if err != nil {
+		logger.Warn("Failed to cache results for %q: %v", query, err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, log := newFakeCSE(t, 4)
+	searchCache = newResponseCache(t.TempDir(), time.Hour, pool)
+	defer func() { searchCache = nil }()
+
+	targets := []Target{{Domain: "example.com"}}
+	processDomains(context.Background(), targets, pool)
+	resetResults()
+	searches := processDomains(context.Background(), targets, pool)
+
+	if n := len(log.all()); n != 1 {
+		t.Errorf("%d API requests, want the second run served from cache", n)
+	}
+	if searches["example.com"].Count != 4 || len(collectedResults()) != 4 {
+		t.Errorf("cached run found %d results, want 4", searches["example.com"].Count)
+	}
+
+	var out strings.Builder
+	printDryRun(&out, append(targets, Target{Domain: "example.org"}))
+	lines := strings.Split(out.String(), "\n")
+	if fields := strings.Fields(lines[1]); fields[0] != "example.com" || fields[2] != "1" || fields[4] != "0" {
+		t.Errorf("cached query line = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "example.org" || fields[2] != "0" || fields[4] != "1-10" {
+		t.Errorf("uncached query line = %q", lines[2])
+	}
+	if !strings.Contains(out.String(), "2 queries: 1 fully cached, 1 need the API. 1 pages from cache, 1-10 API calls.") {
+		t.Errorf("summary missing:\n%s", out.String())
+	}
+
+	searchCache.ttl = 0
+	if _, ok := searchCache.get("site:example.com", 1, 10); ok {
+		t.Error("expired entry was served")
+	}
+}
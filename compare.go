@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EngineComparison is the result of running one target's query on every
+// distinct CSE ID. Shared holds URLs every engine returned and Unique the
+// URLs only one engine returned, keyed by CSE ID.
+type EngineComparison struct {
+	Domain string              `json:"domain"`
+	Query  string              `json:"query"`
+	Shared []string            `json:"shared"`
+	Unique map[string][]string `json:"unique"`
+	Errors map[string]string   `json:"errors,omitempty"`
+}
+
+// distinctEngines returns the first key for each distinct CSE ID, so an
+// engine paired with several keys is only queried once.
+func distinctEngines(pool *KeyPool) []*APIKey {
+	seen := make(map[string]bool)
+	var engines []*APIKey
+	for _, key := range pool.keys {
+		if !seen[key.cseID] {
+			seen[key.cseID] = true
+			engines = append(engines, key)
+		}
+	}
+	return engines
+}
+
+// fetchEngineURLs pages through query on one CSE ID. Each page takes a key
+// for that engine from the pool, like performSearch, so slots, quota,
+// -max-cost and per-key usage apply to the comparison too.
+func fetchEngineURLs(ctx context.Context, pool *KeyPool, cseID, query, domain string) ([]string, error) {
+	var urls []string
+	for start := int64(1); start < 100; {
+		key, err := pool.AcquireEngine(ctx, 0, cseID)
+		if err != nil {
+			return urls, err
+		}
+		resp, err := searchWithRetry(ctx, newListCall(key.svc, cseID, query, start, pageSize(start, apiResultCeiling, 10)), domain)
+		pool.Release(key, err)
+		if err != nil && classifyError(err) == ErrorQuota {
+			// Release took the key out of the pool; retry the page on
+			// another key for the same engine.
+			continue
+		}
+		if err != nil {
+			return urls, err
+		}
+		pool.Served(key, domain)
+		for _, item := range resp.Items {
+			urls = append(urls, item.Link)
+		}
+		if len(resp.Items) < 10 {
+			break
+		}
+		start += 10
+		time.Sleep(pageDelay)
+	}
+	return urls, nil
+}
+
+func compareEngines(ctx context.Context, pool *KeyPool, targets []Target) []EngineComparison {
+	engines := distinctEngines(pool)
+	var comparisons []EngineComparison
+	for _, target := range targets {
+		query := constructQuery(target.Domain, target.Query)
+		comparison := EngineComparison{
+			Domain: target.Domain,
+			Query:  query,
+			Unique: make(map[string][]string),
+		}
+
+		seenBy := make(map[string][]string)
+		succeeded := 0
+		for _, engine := range engines {
+			urls, err := fetchEngineURLs(ctx, pool, engine.cseID, query, target.Domain)
+			if err != nil {
+				logger.Error("Engine %s failed for %s: %v", engine.cseID, target.Domain, err)
+				if comparison.Errors == nil {
+					comparison.Errors = make(map[string]string)
+				}
+				comparison.Errors[engine.cseID] = describeEngineError(err)
+				continue
+			}
+			succeeded++
+			comparison.Unique[engine.cseID] = []string{}
+			for _, u := range urls {
+				if by := seenBy[u]; len(by) == 0 || by[len(by)-1] != engine.cseID {
+					seenBy[u] = append(by, engine.cseID)
+				}
+			}
+		}
+
+		comparison.Shared = []string{}
+		for u, by := range seenBy {
+			switch {
+			case len(by) == succeeded:
+				comparison.Shared = append(comparison.Shared, u)
+			case len(by) == 1:
+				comparison.Unique[by[0]] = append(comparison.Unique[by[0]], u)
+			}
+		}
+		sort.Strings(comparison.Shared)
+		for _, urls := range comparison.Unique {
+			sort.Strings(urls)
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+func writeComparison(w io.Writer, comparisons []EngineComparison, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(comparisons, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	var out strings.Builder
+	for _, comparison := range comparisons {
+		fmt.Fprintf(&out, "%s (%s)\n", comparison.Domain, comparison.Query)
+		fmt.Fprintf(&out, "  shared by all engines: %d\n", len(comparison.Shared))
+		for _, u := range comparison.Shared {
+			fmt.Fprintf(&out, "    %s\n", u)
+		}
+
+		engines := make([]string, 0, len(comparison.Unique))
+		for cseID := range comparison.Unique {
+			engines = append(engines, cseID)
+		}
+		sort.Strings(engines)
+		for _, cseID := range engines {
+			fmt.Fprintf(&out, "  only %s: %d\n", cseID, len(comparison.Unique[cseID]))
+			for _, u := range comparison.Unique[cseID] {
+				fmt.Fprintf(&out, "    %s\n", u)
+			}
+		}
+		failed := make([]string, 0, len(comparison.Errors))
+		for cseID := range comparison.Errors {
+			failed = append(failed, cseID)
+		}
+		sort.Strings(failed)
+		for _, cseID := range failed {
+			fmt.Fprintf(&out, "  %s failed: %s\n", cseID, comparison.Errors[cseID])
+		}
+		out.WriteString("\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
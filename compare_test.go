@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/option"
+)
+
+func TestCompareEngines(t *testing.T) {
+	setupLogger()
+	links := map[string][]string{
+		"cx-a": {"https://example.com/shared", "https://example.com/a-only", "https://example.com/a-only"},
+		"cx-b": {"https://example.com/shared", "https://example.com/b-only"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items []map[string]string
+		for _, link := range links[r.URL.Query().Get("cx")] {
+			items = append(items, map[string]string{"link": link})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	}))
+	defer server.Close()
+
+	svc, err := customsearch.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cx-a's first key is out of quota, so its page moves to key3.
+	pool := &KeyPool{slots: 1, keys: []*APIKey{
+		quotaKey(t, "cx-a"),
+		{svc: svc, cseID: "cx-b", name: "key2", health: 1},
+		{svc: svc, cseID: "cx-a", name: "key3", health: 1},
+	}}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	comparisons := compareEngines(context.Background(), pool, []Target{{Domain: "example.com"}})
+	if len(comparisons) != 1 {
+		t.Fatalf("got %d comparisons, want 1", len(comparisons))
+	}
+	got := comparisons[0]
+	if len(got.Errors) != 0 {
+		t.Errorf("errors = %v, want the spent key's page retried on key3", got.Errors)
+	}
+	usage := pool.Usage()
+	if !usage[0].Exhausted || usage[1].Queries != 1 || usage[2].Queries != 1 {
+		t.Errorf("key usage = %+v, want the spent key exhausted and one query each on key2 and key3", usage)
+	}
+	if strings.Join(got.Shared, ",") != "https://example.com/shared" {
+		t.Errorf("shared = %v", got.Shared)
+	}
+	if strings.Join(got.Unique["cx-a"], ",") != "https://example.com/a-only" {
+		t.Errorf("only cx-a = %v", got.Unique["cx-a"])
+	}
+	if strings.Join(got.Unique["cx-b"], ",") != "https://example.com/b-only" {
+		t.Errorf("only cx-b = %v", got.Unique["cx-b"])
+	}
+
+	var out strings.Builder
+	if err := writeComparison(&out, comparisons, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"shared by all engines: 1", "only cx-a: 1", "only cx-b: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, out.String())
+		}
+	}
+}
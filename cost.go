@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// queryPrice is what the Custom Search API bills per query beyond the free
+// daily queries: $5 per 1000.
+const queryPrice = 5.0 / 1000
+
+// errCostCapReached is returned by AcquireEngine once another query would
+// take the estimated spend past -max-cost.
+var errCostCapReached = errors.New("the -max-cost spending cap is reached")
+
+// billableQueries is how many queries over the free tier the keys have
+// been handed out for. Each key is assumed to have its own free queries.
+func (p *KeyPool) billableQueries() int {
+	billable := 0
+	for _, key := range p.keys {
+		billable += max(0, key.issued-freeDailyQueries)
+	}
+	return billable
+}
+
+// overCostCap reports whether one more query on key would cost more than
+// -max-cost allows. p.mu must be held.
+func (p *KeyPool) overCostCap(key *APIKey) bool {
+	if *maxCost <= 0 || key.issued < freeDailyQueries {
+		return false
+	}
+	// The small epsilon keeps e.g. $0.05 from rounding down to 9 queries.
+	allowed := int(math.Floor(*maxCost/queryPrice + 1e-9))
+	return p.billableQueries() >= allowed
+}
+
+// EstimatedSpend is what the queries of this run cost at the list price,
+// beyond each key's free daily queries.
+func (p *KeyPool) EstimatedSpend() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return float64(p.billableQueries()) * queryPrice
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMaxCost(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*maxCost = 0.01
+	pageDelay = 0
+	defer func() { *maxCost, pageDelay = 0, time.Second }()
+
+	pool, log := newFakeCSE(t, 50)
+	// The free queries are used up, so $0.01 buys two more pages.
+	pool.keys[0].issued = freeDailyQueries
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	if requests := len(log.all()); requests != 2 {
+		t.Errorf("%d requests, want 2 within -max-cost $0.01", requests)
+	}
+	if spend := pool.EstimatedSpend(); math.Abs(spend-0.01) > 1e-9 {
+		t.Errorf("estimated spend = %v, want 0.01", spend)
+	}
+	if search := searches["example.com"]; !search.Partial || search.Count != 20 {
+		t.Errorf("count %d, partial %v; want the 20 results before the cap kept as partial", search.Count, search.Partial)
+	}
+
+	// Without a cap, nothing is billed until the free queries run out.
+	*maxCost = 0
+	free, _ := newFakeCSE(t, 5)
+	processDomains(context.Background(), []Target{{Domain: "example.com"}}, free)
+	if spend := free.EstimatedSpend(); spend != 0 {
+		t.Errorf("estimated spend within the free tier = %v, want 0", spend)
+	}
+}
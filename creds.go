@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// credentialFlags collects repeated -api-key or -cse-id flags. API keys are
+// masked whenever the value is printed, as by -print-config.
+type credentialFlags struct {
+	values []string
+	secret bool
+}
+
+var (
+	apiKeyFlags = &credentialFlags{secret: true}
+	cseIDFlags  = &credentialFlags{}
+)
+
+func init() {
+	flag.Var(apiKeyFlags, "api-key", "Google API key; with -cse-id, replaces the config file and environment entirely (repeatable)")
+	flag.Var(cseIDFlags, "cse-id", "CSE ID; with -api-key, replaces the config file and environment entirely (repeatable)")
+}
+
+func (c *credentialFlags) String() string {
+	if c == nil {
+		return ""
+	}
+	values := c.values
+	if c.secret {
+		values = make([]string, len(c.values))
+		for i, value := range c.values {
+			values[i] = maskKey(value)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// flagValues returns the credentials in the order they were given.
+func (c *credentialFlags) flagValues() []string {
+	return c.values
+}
+
+func (c *credentialFlags) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return fmt.Errorf("empty value")
+	}
+	c.values = append(c.values, s)
+	return nil
+}
+
+// flagConfig returns the credentials given with -api-key and -cse-id.
+func flagConfig() Config {
+	return Config{GoogleAPI: apiKeyFlags.values, GoogleCSEID: cseIDFlags.values}
+}
+
+// credentialsFromFlags reports whether -api-key or -cse-id was given. The
+// run then takes its credentials from the flags alone, and neither the
+// config file nor the environment is read.
+func credentialsFromFlags() bool {
+	return len(apiKeyFlags.values) > 0 || len(cseIDFlags.values) > 0
+}
+
+// credentialOverrides returns the credentials that replace the config
+// file's: those from -api-key and -cse-id when given, otherwise those from
+// the environment.
+func credentialOverrides() Config {
+	if credentialsFromFlags() {
+		return flagConfig()
+	}
+	return envConfig()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestCredentialFlags(t *testing.T) {
+	setupLogger()
+	t.Setenv(envAPIKey, "env-key")
+	t.Setenv(envCSEID, "env-cx")
+	apiKeyFlags.Set("AIzaFlagSecretKey1234")
+	cseIDFlags.Set("flag-cx-1")
+	cseIDFlags.Set("flag-cx-2")
+	defer func() { apiKeyFlags.values, cseIDFlags.values = nil, nil }()
+
+	if !hasCredentials(flagConfig()) {
+		t.Error("flag credentials should be complete without a config file")
+	}
+	config := loadAPIConfig("")
+	if strings.Join(config.GoogleAPI, ",") != "AIzaFlagSecretKey1234" || strings.Join(config.GoogleCSEID, ",") != "flag-cx-1,flag-cx-2" {
+		t.Errorf("flags should replace the environment credentials: %+v", config)
+	}
+
+	// A lone -api-key is not completed from GOOGLE_CSE_ID: flags bypass the
+	// environment entirely.
+	cseIDFlags.values = nil
+	if config := credentialOverrides(); hasCredentials(config) || len(config.GoogleCSEID) > 0 {
+		t.Errorf("-api-key alone picked up credentials from the environment: %+v", config)
+	}
+	cseIDFlags.values = []string{"flag-cx-1", "flag-cx-2"}
+
+	if got := apiKeyFlags.String(); got != "AIza...1234" {
+		t.Errorf("-api-key prints as %q, want it masked", got)
+	}
+	if got := cseIDFlags.String(); got != "flag-cx-1,flag-cx-2" {
+		t.Errorf("-cse-id prints as %q", got)
+	}
+	if err := apiKeyFlags.Set(" "); err == nil {
+		t.Error("an empty -api-key should be rejected")
+	}
+
+	flags := flag.NewFlagSet("go-dork-google", flag.ContinueOnError)
+	flags.Var(apiKeyFlags, "api-key", "")
+	flags.Parse([]string{"-api-key", "AIzaOtherSecretKey5678"})
+	if cmd := buildCommand(flags, nil); strings.Contains(cmd, "Secret") || !strings.Contains(cmd, "-api-key REDACTED") {
+		t.Errorf("-emit-command leaks the API key:\n%s", cmd)
+	}
+}
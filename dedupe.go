@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// normalizeText reduces a title or snippet to its words, so results that
+// differ only in case, punctuation, whitespace or numbers, such as "Page 2"
+// and "Page 3" of a listing, compare equal.
+func normalizeText(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}), " ")
+}
+
+// dedupeSnippets drops every result whose normalized title and snippet
+// match an earlier result for the same domain, and returns how many were
+// dropped. Results without any text are always kept.
+func dedupeSnippets(results []Result) ([]Result, int) {
+	seen := make(map[string]bool)
+	kept := results[:0:0]
+	for _, result := range results {
+		title, snippet := normalizeText(result.Title), normalizeText(result.Snippet)
+		if title == "" && snippet == "" {
+			kept = append(kept, result)
+			continue
+		}
+		key := result.Domain + "\x00" + title + "\x00" + snippet
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, result)
+	}
+	return kept, len(results) - len(kept)
+}
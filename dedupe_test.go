@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestDedupeSnippets(t *testing.T) {
+	results := []Result{
+		{Domain: "example.com", URL: "https://example.com/tag/a?page=1", Title: "Posts - Page 1", Snippet: "Latest posts from our blog..."},
+		{Domain: "example.com", URL: "https://example.com/tag/a?page=2", Title: "Posts – Page 2", Snippet: "Latest  posts from our blog"},
+		{Domain: "example.org", URL: "https://example.org/tag/a", Title: "Posts - Page 1", Snippet: "Latest posts from our blog..."},
+		{Domain: "example.com", URL: "https://example.com/login", Title: "Login", Snippet: "Sign in to your account"},
+		{Domain: "example.com", URL: "https://example.com/a.pdf"},
+		{Domain: "example.com", URL: "https://example.com/b.pdf"},
+	}
+
+	kept, dropped := dedupeSnippets(results)
+	if dropped != 1 || len(kept) != 5 {
+		t.Fatalf("kept %d, dropped %d; want 5 and 1", len(kept), dropped)
+	}
+	for i, want := range []string{"https://example.com/tag/a?page=1", "https://example.org/tag/a", "https://example.com/login", "https://example.com/a.pdf", "https://example.com/b.pdf"} {
+		if kept[i].URL != want {
+			t.Errorf("kept[%d] = %s, want %s", i, kept[i].URL, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "time"
+
+// deepPaginateMonths is how far back -deep-paginate reaches, one window per
+// month.
+const deepPaginateMonths = 12
+
+// dateWindows are the date ranges -deep-paginate runs a query in, as
+// YYYYMMDD:YYYYMMDD for the API's sort=date:r: restriction: the current
+// calendar month up to now, then each of the 11 months before it. The
+// windows do not overlap, so each one's first 100 results are new ones.
+func dateWindows(now time.Time) []string {
+	const layout = "20060102"
+	windows := make([]string, 0, deepPaginateMonths)
+	for month := 0; month < deepPaginateMonths; month++ {
+		first := time.Date(now.Year(), now.Month()-time.Month(month), 1, 0, 0, 0, 0, now.Location())
+		last := first.AddDate(0, 1, -1)
+		if month == 0 {
+			last = now
+		}
+		windows = append(windows, first.Format(layout)+":"+last.Format(layout))
+	}
+	return windows
+}
+
+// expandDateWindows keeps every target as it is, which also finds results
+// older than a year, and adds a copy of it per date window, for
+// -deep-paginate.
+func expandDateWindows(targets []Target, now time.Time) []Target {
+	windows := dateWindows(now)
+	expanded := make([]Target, 0, len(targets)*(len(windows)+1))
+	for _, target := range targets {
+		expanded = append(expanded, target)
+		for _, window := range windows {
+			restricted := target
+			restricted.DateRange = window
+			expanded = append(expanded, restricted)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateWindows(t *testing.T) {
+	windows := dateWindows(time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC))
+	if len(windows) != 12 {
+		t.Fatalf("%d windows, want 12", len(windows))
+	}
+	want := map[int]string{0: "20260301:20260331", 1: "20260201:20260228", 2: "20260101:20260131", 3: "20251201:20251231", 11: "20250401:20250430"}
+	for i, w := range want {
+		if windows[i] != w {
+			t.Errorf("window %d = %s, want %s", i, windows[i], w)
+		}
+	}
+	// Each window starts the day after the next older one ends.
+	for i := 0; i+1 < len(windows); i++ {
+		start, _ := time.Parse("20060102", strings.Split(windows[i], ":")[0])
+		olderEnd, _ := time.Parse("20060102", strings.Split(windows[i+1], ":")[1])
+		if !olderEnd.AddDate(0, 0, 1).Equal(start) {
+			t.Errorf("windows %s and %s overlap or leave a gap", windows[i+1], windows[i])
+		}
+	}
+}
+
+func TestDeepPaginate(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, log := newFakeCSE(t, 3)
+
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	targets := expandDateWindows([]Target{{Domain: "example.com", Query: "ext:pdf"}}, now)
+	if len(targets) != 13 || targets[0].DateRange != "" || targets[1].DateRange != "20261001:20261015" || targets[12].DateRange != "20251101:20251130" {
+		t.Fatalf("targets = %+v, want the query unrestricted and in 12 monthly windows", targets)
+	}
+	if cacheQuery(targets[0], "q") == cacheQuery(targets[3], "q") {
+		t.Error("a date window shares its cache entry with the unrestricted query")
+	}
+
+	searches := processDomains(context.Background(), targets, pool)
+	windows := make(map[string]int)
+	for _, request := range log.all() {
+		windows[request.Get("sort")]++
+	}
+	if windows[""] != 1 {
+		t.Errorf("unrestricted query ran %d times, want once", windows[""])
+	}
+	for _, window := range dateWindows(now) {
+		if windows["date:r:"+window] != 1 {
+			t.Errorf("window %q queried %d times, want once", window, windows["date:r:"+window])
+		}
+	}
+
+	found := dedupeDomainURLs(collectedResults(), searches)
+	if len(found) != 3 || searches["example.com"].Count != 3 {
+		t.Errorf("%d results, count %d; want the 3 distinct URLs", len(found), searches["example.com"].Count)
+	}
+}
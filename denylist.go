@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// urlDenylist holds the -url-denylist entries. It is nil without the flag.
+var urlDenylist *denylist
+
+// denylist matches result URLs against exact entries and glob patterns,
+// where * matches any run of characters, including '/', and ? exactly one.
+type denylist struct {
+	exact      map[string]bool
+	patterns   []*regexp.Regexp
+	suppressed atomic.Int64
+}
+
+// loadDenylist reads one URL or pattern per line. Blank lines and lines
+// starting with # are ignored.
+func loadDenylist(path string) (*denylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &denylist{exact: make(map[string]bool)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.ContainsAny(line, "*?") {
+			list.exact[line] = true
+			continue
+		}
+		pattern := regexp.QuoteMeta(line)
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		list.patterns = append(list.patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+	return list, scanner.Err()
+}
+
+// denies reports whether link is on the list, counting it as suppressed.
+func (l *denylist) denies(link string) bool {
+	if l == nil {
+		return false
+	}
+	denied := l.exact[link]
+	for _, pattern := range l.patterns {
+		if denied {
+			break
+		}
+		denied = pattern.MatchString(link)
+	}
+	if denied {
+		l.suppressed.Add(1)
+	}
+	return denied
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDenylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	os.WriteFile(path, []byte("# known noise\nhttps://example.com/status\n\nhttps://web.archive.org/*\nhttps://example.com/page?.html\n"), 0644)
+	list, err := loadDenylist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"https://example.com/status":                                 true,
+		"https://example.com/status/":                                false,
+		"https://web.archive.org/web/2020/https://example.com/login": true,
+		"https://example.com/page1.html":                             true,
+		"https://example.com/page10.html":                            false,
+		"https://example.com/login":                                  false,
+	}
+	for link, want := range tests {
+		if got := list.denies(link); got != want {
+			t.Errorf("denies(%q) = %v, want %v", link, got, want)
+		}
+	}
+	if got := list.suppressed.Load(); got != 3 {
+		t.Errorf("suppressed = %d, want 3", got)
+	}
+
+	var none *denylist
+	if none.denies("https://example.com/status") {
+		t.Error("a nil denylist denied a URL")
+	}
+}
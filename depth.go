@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// subdomainDepth counts the labels of host beyond its registrable domain, so
+// a.b.example.com and a.b.example.co.uk both have depth 2. Hosts without a
+// registrable domain, such as IP addresses, have depth 0.
+func subdomainDepth(host string) int {
+	if net.ParseIP(host) != nil {
+		return 0
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(host, ".") - strings.Count(registrable, ".")
+}
+
+// filterSubdomainDepth drops subdomains shallower than minDepth for
+// -min-depth.
+func filterSubdomainDepth(results map[string]SearchResult, minDepth int) map[string]SearchResult {
+	filtered := make(map[string]SearchResult, len(results))
+	for domain, result := range results {
+		deep := []string{}
+		for _, sub := range result.Subdomains {
+			if subdomainDepth(sub) >= minDepth {
+				deep = append(deep, sub)
+			}
+		}
+		result.Subdomains = deep
+		filtered[domain] = result
+	}
+	return filtered
+}
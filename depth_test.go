@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubdomainDepth(t *testing.T) {
+	tests := map[string]int{
+		"example.com":          0,
+		"a.example.com":        1,
+		"a.b.example.com":      2,
+		"a.b.example.co.uk":    2,
+		"shop.example.co.uk":   1,
+		"x.y.z.example.com.au": 3,
+		"192.168.0.1":          0,
+	}
+	for host, want := range tests {
+		if got := subdomainDepth(host); got != want {
+			t.Errorf("subdomainDepth(%q) = %d, want %d", host, got, want)
+		}
+	}
+}
+
+func TestFilterSubdomainDepth(t *testing.T) {
+	results := map[string]SearchResult{
+		"example.com":   {Domain: "example.com", Subdomains: []string{"a.b.example.com", "a.example.com", "x.y.z.example.com"}},
+		"example.co.uk": {Domain: "example.co.uk", Subdomains: []string{"shop.example.co.uk"}},
+	}
+	got := filterSubdomainDepth(results, 2)
+	if want := []string{"a.b.example.com", "x.y.z.example.com"}; !reflect.DeepEqual(got["example.com"].Subdomains, want) {
+		t.Errorf("example.com subdomains = %v, want %v", got["example.com"].Subdomains, want)
+	}
+	if subs := got["example.co.uk"].Subdomains; len(subs) != 0 {
+		t.Errorf("example.co.uk subdomains = %v, want none", subs)
+	}
+}
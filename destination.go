@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+
+// destination is one place a run's results are delivered to, such as the
+// output file, Elasticsearch or a webhook. A failed remote destination is
+// reported but does not change the exit status.
+type destination struct {
+	name   string
+	remote bool
+	write  func() error
+}
+
+// DestinationStatus records how one destination fared, for the run summary.
+type DestinationStatus struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+
+	remote bool
+}
+
+// deliver writes to every destination in order. A failing destination is
+// logged and reported but does not stop the ones after it.
+func deliver(destinations []destination) []DestinationStatus {
+	statuses := make([]DestinationStatus, 0, len(destinations))
+	for _, dest := range destinations {
+		status := DestinationStatus{Name: dest.name, OK: true, remote: dest.remote}
+		if err := dest.write(); err != nil {
+			logger.Error("Failed to write %s: %v", dest.name, err)
+			status.OK, status.Error = false, err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// destinationsErr summarizes failed local destinations as a single error.
+func destinationsErr(statuses []DestinationStatus) error {
+	local, failed := 0, 0
+	for _, status := range statuses {
+		if status.remote {
+			continue
+		}
+		local++
+		if !status.OK {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d output destinations failed", failed, local)
+}
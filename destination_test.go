@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeliverContinuesAfterFailure(t *testing.T) {
+	setupLogger()
+	var wrote []string
+	statuses := deliver([]destination{
+		{name: "output", write: func() error { wrote = append(wrote, "output"); return nil }},
+		{name: "-explode", write: func() error { return errors.New("disk full") }},
+		{name: "-elasticsearch", remote: true, write: func() error { return errors.New("connection refused") }},
+		{name: "-webhook", remote: true, write: func() error { wrote = append(wrote, "webhook"); return nil }},
+	})
+
+	if len(wrote) != 2 || wrote[1] != "webhook" {
+		t.Errorf("wrote %v, want output and webhook", wrote)
+	}
+	want := []DestinationStatus{
+		{Name: "output", OK: true},
+		{Name: "-explode", Error: "disk full"},
+		{Name: "-elasticsearch", Error: "connection refused", remote: true},
+		{Name: "-webhook", OK: true, remote: true},
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("status %d = %+v, want %+v", i, statuses[i], want[i])
+		}
+	}
+	if err := destinationsErr(statuses); err == nil || err.Error() != "1 of 2 output destinations failed" {
+		t.Errorf("destinationsErr = %v", err)
+	}
+	// Remote destinations are reported but do not fail the run.
+	if err := destinationsErr([]DestinationStatus{statuses[0], statuses[2]}); err != nil {
+		t.Errorf("destinationsErr with only a remote failure = %v", err)
+	}
+}
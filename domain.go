@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// normalizeDomain turns a pasted domain such as "https://Example.com:443/path"
+// or "example.com." into the bare host that site: expects: no scheme,
+// credentials, port, path or trailing dot, lowercased. A leading "*." from a
+// wildcard scope entry is dropped as well.
+func normalizeDomain(input string) string {
+	domain := strings.TrimSpace(input)
+	if _, rest, ok := strings.Cut(domain, "://"); ok {
+		domain = rest
+	}
+	if i := strings.IndexAny(domain, "/?#"); i >= 0 {
+		domain = domain[:i]
+	}
+	if i := strings.LastIndex(domain, "@"); i >= 0 {
+		domain = domain[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	domain = strings.TrimPrefix(domain, "*.")
+	return strings.TrimRight(strings.ToLower(domain), ".")
+}
+
+// cleanDomain normalizes a domain from the command line or a domain list,
+// warning when that changes it. Both forms are hidden by -redact.
+func cleanDomain(input string) string {
+	redactTarget(input)
+	domain := normalizeDomain(input)
+	redactTarget(domain)
+	if domain != "" && domain != input {
+		logger.Warn("Using domain %q for input %q", domain, input)
+	}
+	return domain
+}
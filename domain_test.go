@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                     "example.com",
+		"https://Example.com/":            "example.com",
+		"example.com:443":                 "example.com",
+		"example.com.":                    "example.com",
+		"HTTP://Sub.Example.com:8080/a?b": "sub.example.com",
+		"  example.com  ":                 "example.com",
+		"user:pass@example.com":           "example.com",
+		"https://user@example.com/x@y":    "example.com",
+		"*.example.com":                   "example.com",
+		"example.com/path#frag":           "example.com",
+		"[2001:db8::1]:443":               "2001:db8::1",
+		"https://":                        "",
+	}
+	for input, want := range tests {
+		if got := normalizeDomain(input); got != want {
+			t.Errorf("normalizeDomain(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseTargetLineNormalizes(t *testing.T) {
+	setupLogger()
+	target, err := parseTargetLine("https://Example.com./ | inurl:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Domain != "example.com" || target.Query != "inurl:admin" {
+		t.Errorf("target = %+v", target)
+	}
+	if _, err := parseTargetLine("https:// | inurl:admin"); err == nil {
+		t.Error("accepted a line with no host")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var macroNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// varFlags collects repeated -var key=value flags for dork macros.
+type varFlags map[string]string
+
+var dorkVars = varFlags{}
+
+func init() {
+	flag.Var(dorkVars, "var", "Define a dork macro as key=value, usable as {{key}} in -dorks files (repeatable)")
+}
+
+func (v varFlags) String() string {
+	return strings.Join(v.flagValues(), ",")
+}
+
+// flagValues returns each macro as the key=value given to -var.
+func (v varFlags) flagValues() []string {
+	pairs := make([]string, 0, len(v))
+	for key, value := range v {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+func (v varFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	if !macroNameRe.MatchString(key) {
+		return fmt.Errorf("invalid macro name %q", key)
+	}
+	if key == "domain" || key == "year" {
+		return fmt.Errorf("%q is a built-in macro and cannot be redefined", key)
+	}
+	v[key] = value
+	return nil
+}
+
+// macroFuncs exposes the built-in {{domain}} and {{year}} macros and every
+// -var as template functions, so an undefined name fails at parse time.
+func macroFuncs(domain string) template.FuncMap {
+	funcs := template.FuncMap{
+		"domain": func() string { return domain },
+		"year":   func() string { return strconv.Itoa(time.Now().Year()) },
+	}
+	for key, value := range dorkVars {
+		value := value
+		funcs[key] = func() string { return value }
+	}
+	return funcs
+}
+
+func parseDork(dork, domain string) (*template.Template, error) {
+	return template.New("dork").Funcs(macroFuncs(domain)).Parse(dork)
+}
+
+// loadDorks reads one dork per line, skipping blank lines and # comments.
+// Every dork is parsed up front so a typo or undefined macro is reported
+// before any quota is spent.
+func loadDorks(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dorks []string
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := parseDork(line, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineNo, strings.TrimPrefix(err.Error(), "template: dork:1: "))
+		}
+		dorks = append(dorks, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(dorks) == 0 {
+		return nil, fmt.Errorf("%s contains no dorks", filename)
+	}
+	return dorks, nil
+}
+
+func expandDork(dork, domain string) (string, error) {
+	tmpl, err := parseDork(dork, domain)
+	if err != nil {
+		return "", err
+	}
+	var query strings.Builder
+	if err := tmpl.Execute(&query, nil); err != nil {
+		return "", err
+	}
+	return query.String(), nil
+}
+
+// expandDorks replaces each target with one search per dork, using the
+// expanded dork in place of the target's query.
+func expandDorks(targets []Target, dorks []string) ([]Target, error) {
+	expanded := make([]Target, 0, len(targets)*len(dorks))
+	for _, target := range targets {
+		for _, dork := range dorks {
+			query, err := expandDork(dork, target.Domain)
+			if err != nil {
+				return nil, fmt.Errorf("dork %q for %s: %v", dork, target.Domain, err)
+			}
+			expanded = append(expanded, Target{Domain: target.Domain, Query: query, Dork: dork})
+		}
+	}
+	return expanded, nil
+}
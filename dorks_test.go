@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandDorks(t *testing.T) {
+	if err := dorkVars.Set("client=acme"); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(dorkVars, "client")
+
+	path := filepath.Join(t.TempDir(), "dorks.txt")
+	os.WriteFile(path, []byte("# logs\nfiletype:log intext:{{domain}}\n\nintext:{{client}} after:{{year}}\n"), 0644)
+	dorks, err := loadDorks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := expandDorks([]Target{{Domain: "example.com"}, {Domain: "example.org"}}, dorks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	year := strconv.Itoa(time.Now().Year())
+	want := []Target{
+		{Domain: "example.com", Query: "filetype:log intext:example.com", Dork: "filetype:log intext:{{domain}}"},
+		{Domain: "example.com", Query: "intext:acme after:" + year, Dork: "intext:{{client}} after:{{year}}"},
+		{Domain: "example.org", Query: "filetype:log intext:example.org", Dork: "filetype:log intext:{{domain}}"},
+		{Domain: "example.org", Query: "intext:acme after:" + year, Dork: "intext:{{client}} after:{{year}}"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d: %v", len(targets), len(want), targets)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("target %d = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+}
+
+func TestLoadDorksUndefinedMacro(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dorks.txt")
+	os.WriteFile(path, []byte("site:{{domain}}\nintext:{{client}}\n"), 0644)
+
+	_, err := loadDorks(path)
+	if err == nil || !strings.Contains(err.Error(), ":2:") || !strings.Contains(err.Error(), `"client" not defined`) {
+		t.Errorf("err = %v, want undefined client macro on line 2", err)
+	}
+}
+
+func TestVarFlagsRejectsBuiltins(t *testing.T) {
+	for _, arg := range []string{"domain=x", "year=1999", "no-equals", "1bad=x"} {
+		if err := (varFlags{}).Set(arg); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", arg)
+		}
+	}
+}
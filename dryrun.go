@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// queryPlan is what -dry-run expects a query to cost: the leading pages the
+// cache can serve, and the API calls needed after them.
+type queryPlan struct {
+	Domain      string
+	Query       string
+	CachedPages int
+	CacheAge    time.Duration
+	MinCalls    int
+	MaxCalls    int
+}
+
+// planQuery walks the cached pages of a query the way performSearch would.
+// Once a page is missing or expired, every later page needs the API, but
+// how many of them exist is only known by fetching them.
+func planQuery(target Target) queryPlan {
+	plan := queryPlan{Domain: target.Domain, Query: constructQuery(target.Domain, target.Query)}
+	total, perPage := searchLimits()
+	for start := int64(1); start <= total; start += perPage {
+		num := pageSize(start, total, perPage)
+		resp, age, ok := searchCache.lookup(cacheQuery(target, plan.Query), start, num)
+		if !ok || age > searchCache.ttl {
+			plan.MinCalls, plan.MaxCalls = 1, int((total-start)/perPage)+1
+			return plan
+		}
+		plan.CachedPages++
+		if age > plan.CacheAge {
+			plan.CacheAge = age
+		}
+		if len(resp.Items) < int(num) {
+			break
+		}
+	}
+	return plan
+}
+
+// printDryRun prints the plan for every target without calling the API, and a
+// summary of cache hits and the quota the run would cost.
+func printDryRun(w io.Writer, targets []Target) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tQUERY\tCACHED PAGES\tCACHE AGE\tAPI CALLS")
+
+	var cachedPages, hits, minCalls, maxCalls int
+	for _, target := range targets {
+		plan := planQuery(target)
+		cachedPages += plan.CachedPages
+		minCalls += plan.MinCalls
+		maxCalls += plan.MaxCalls
+
+		age, calls := "-", "0"
+		if plan.CachedPages > 0 {
+			age = plan.CacheAge.Round(time.Second).String()
+		}
+		if plan.MaxCalls > 0 {
+			calls = fmt.Sprintf("%d-%d", plan.MinCalls, plan.MaxCalls)
+		} else {
+			hits++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", plan.Domain, plan.Query, plan.CachedPages, age, calls)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d queries: %d fully cached, %d need the API. %d pages from cache, %d-%d API calls.\n",
+		len(targets), hits, len(targets)-hits, cachedPages, minCalls, maxCalls)
+}
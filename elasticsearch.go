@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// postElasticsearch indexes docs into the index at indexURL, e.g.
+// http://localhost:9200/dorks, with a single _bulk request. Credentials in
+// the URL are sent as basic auth.
+func postElasticsearch(client *http.Client, indexURL string, docs []any) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		body.WriteString(`{"index":{}}` + "\n")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.Post(strings.TrimSuffix(indexURL, "/")+"/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("elasticsearch returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	// A bulk request succeeds as a whole even when single documents fail.
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid elasticsearch response: %v", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	for _, item := range result.Items {
+		for _, action := range item {
+			if len(action.Error) > 0 {
+				failed++
+			}
+		}
+	}
+	return fmt.Errorf("elasticsearch rejected %d of %d documents", failed, len(docs))
+}
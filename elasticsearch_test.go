@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostElasticsearch(t *testing.T) {
+	var lines []string
+	var path, contentType string
+	reject := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		lines = nil
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if reject {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	docs := []any{
+		Result{Domain: "example.com", URL: "https://a.example.com/"},
+		Result{Domain: "example.com", URL: "https://b.example.com/"},
+	}
+	if err := postElasticsearch(server.Client(), server.URL+"/dorks/", docs); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/dorks/_bulk" || contentType != "application/x-ndjson" {
+		t.Errorf("posted to %s as %s", path, contentType)
+	}
+	if len(lines) != 4 || lines[0] != `{"index":{}}` || lines[2] != `{"index":{}}` {
+		t.Fatalf("bulk body = %q", lines)
+	}
+	var doc Result
+	if err := json.Unmarshal([]byte(lines[3]), &doc); err != nil || doc.URL != "https://b.example.com/" {
+		t.Errorf("second document = %s (%v)", lines[3], err)
+	}
+
+	reject = true
+	err := postElasticsearch(server.Client(), server.URL+"/dorks", docs)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("partial rejection error = %v", err)
+	}
+}
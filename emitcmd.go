@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretFlags hold credentials in their whole value and are never written
+// out by -emit-command or -print-config.
+var secretFlags = map[string]bool{
+	"webhook": true,
+	"api-key": true,
+}
+
+const redactedValue = "REDACTED"
+
+// repeatedFlag is a flag that can be given several times, such as -var or
+// -param. Its String joins the values with commas, which would replay as a
+// single value, so -emit-command writes one -name value pair per value.
+type repeatedFlag interface {
+	flagValues() []string
+}
+
+// redactFlagValue hides secrets in a flag value: the whole value of a
+// secretFlags flag, and the password in a URL such as -proxy's.
+func redactFlagValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if secretFlags[name] {
+		return redactedValue
+	}
+	if parsed, err := url.Parse(value); err == nil && parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+			return parsed.String()
+		}
+	}
+	return value
+}
+
+// shellQuote quotes s for a POSIX shell when it contains anything but
+// characters that are safe unquoted.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// buildCommand renders the command line that reproduces this run: every flag
+// set on the command line or by the config file, in name order, then the
+// extra domain arguments, then the queries the run resolved to as shell
+// comments.
+func buildCommand(flags *flag.FlagSet, targets []Target) string {
+	args := []string{filepath.Base(flags.Name())}
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name == "emit-command" || !(set[f.Name] || configFlags[f.Name]) {
+			return
+		}
+		value := f.Value.String()
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if value == "true" {
+				args = append(args, "-"+f.Name)
+			} else {
+				args = append(args, "-"+f.Name+"="+value)
+			}
+			return
+		}
+		if r, ok := f.Value.(repeatedFlag); ok {
+			for _, v := range r.flagValues() {
+				args = append(args, "-"+f.Name, shellQuote(redactFlagValue(f.Name, v)))
+			}
+			return
+		}
+		args = append(args, "-"+f.Name, shellQuote(redactFlagValue(f.Name, value)))
+	})
+	for _, arg := range flags.Args() {
+		args = append(args, shellQuote(arg))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(args, " "))
+	b.WriteString("\n")
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		query := constructQuery(target.Domain, target.Query)
+		if seen[query] {
+			continue
+		}
+		seen[query] = true
+		fmt.Fprintf(&b, "# query: %s\n", query)
+	}
+	return b.String()
+}
+
+// emitCommand writes the command to stderr when dest is "-", or to the file
+// dest otherwise.
+func emitCommand(dest string, command string) error {
+	if dest == "-" {
+		_, err := io.WriteString(os.Stderr, command)
+		return err
+	}
+	return writeOutput(dest, []byte(command))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// recipients are the -encrypt-to keys the -o file is encrypted to: either
+// age recipients or PGP public keys, never both.
+type recipients struct {
+	age []age.Recipient
+	pgp openpgp.EntityList
+}
+
+// outputRecipients is nil without -encrypt-to, and output is written as
+// plain text.
+var outputRecipients *recipients
+
+// loadRecipients reads -encrypt-to: one or more comma-separated age
+// recipients ("age1..."), or a file holding either age recipients, one per
+// line, or PGP public keys, armored or binary, e.g. from "gpg --export
+// --armor you@example.com".
+func loadRecipients(spec string) (*recipients, error) {
+	if strings.HasPrefix(spec, "age1") {
+		var to recipients
+		for _, s := range strings.Split(spec, ",") {
+			r, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			to.age = append(to.age, r)
+		}
+		return &to, nil
+	}
+
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err == nil && len(keys) > 0 {
+		return &recipients{pgp: keys}, nil
+	}
+	if ageRecipients, ageErr := age.ParseRecipients(bytes.NewReader(data)); ageErr == nil {
+		return &recipients{age: ageRecipients}, nil
+	}
+	if err == nil {
+		err = errors.New("no keys found")
+	}
+	return nil, fmt.Errorf("%s holds neither age recipients nor a PGP public key: %v", spec, err)
+}
+
+// encryptOutput encrypts data to every recipient as ASCII-armored text: an
+// age file that "age --decrypt" reads, or a PGP message for "gpg --decrypt".
+func encryptOutput(data []byte, to *recipients) ([]byte, error) {
+	var encrypted bytes.Buffer
+	var armored io.WriteCloser
+	var plain io.WriteCloser
+	var err error
+	if to.age != nil {
+		armored = armor.NewWriter(&encrypted)
+		plain, err = age.Encrypt(armored, to.age...)
+	} else {
+		if armored, err = pgparmor.Encode(&encrypted, "PGP MESSAGE", nil); err != nil {
+			return nil, err
+		}
+		plain, err = openpgp.Encrypt(armored, to.pgp, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plain.Write(data); err != nil {
+		return nil, err
+	}
+	if err := plain.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return encrypted.Bytes(), nil
+}
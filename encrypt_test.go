@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	ageArmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+const secretOutput = "https://example.com/backup.sql\n"
+
+func TestEncryptOutput(t *testing.T) {
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var public bytes.Buffer
+	armored, _ := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err := entity.Serialize(armored); err != nil {
+		t.Fatal(err)
+	}
+	armored.Close()
+	keyFile := filepath.Join(t.TempDir(), "key.asc")
+	os.WriteFile(keyFile, public.Bytes(), 0644)
+
+	recipients, err := loadRecipients(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptOutput([]byte(secretOutput), recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(encrypted), "-----BEGIN PGP MESSAGE-----") || bytes.Contains(encrypted, []byte("backup.sql")) {
+		t.Fatalf("encrypted output is not an armored PGP message:\n%s", encrypted)
+	}
+
+	block, err := armor.Decode(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(message.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != secretOutput {
+		t.Errorf("decrypted %q, want the original output", plain)
+	}
+
+	os.WriteFile(keyFile, []byte("not a key"), 0644)
+	if _, err := loadRecipients(keyFile); err == nil {
+		t.Error("loadRecipients accepted a file without a key")
+	}
+}
+
+func TestEncryptOutputAge(t *testing.T) {
+	first, _ := age.GenerateX25519Identity()
+	second, _ := age.GenerateX25519Identity()
+	recipientsFile := filepath.Join(t.TempDir(), "recipients.txt")
+	os.WriteFile(recipientsFile, []byte("# team\n"+second.Recipient().String()+"\n"), 0644)
+
+	for _, tt := range []struct {
+		spec     string
+		identity *age.X25519Identity
+	}{
+		{first.Recipient().String() + "," + second.Recipient().String(), first},
+		{recipientsFile, second},
+	} {
+		recipients, err := loadRecipients(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		encrypted, err := encryptOutput([]byte(secretOutput), recipients)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(encrypted), ageArmor.Header) || bytes.Contains(encrypted, []byte("backup.sql")) {
+			t.Fatalf("encrypted output is not an armored age file:\n%s", encrypted)
+		}
+
+		plain, err := age.Decrypt(ageArmor.NewReader(bytes.NewReader(encrypted)), tt.identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := io.ReadAll(plain); string(got) != secretOutput {
+			t.Errorf("decrypted %q, want the original output", got)
+		}
+	}
+
+	if _, err := loadRecipients("age1notarecipient"); err == nil {
+		t.Error("loadRecipients accepted a malformed age recipient")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/api/googleapi"
+)
+
+// EngineStatus is the outcome of a test query against one key/CSE pair.
+type EngineStatus struct {
+	Key    string
+	CSEID  string
+	OK     bool
+	Status string
+	Title  string
+	Facets []string
+}
+
+type engineContext struct {
+	Title  string `json:"title"`
+	Facets [][]struct {
+		Label       string `json:"label"`
+		Anchor      string `json:"anchor"`
+		LabelWithOp string `json:"label_with_op"`
+	} `json:"facets"`
+}
+
+// describeEngineError turns a failed test query into a short status,
+// calling out quota exhaustion and rejected keys specifically.
+func describeEngineError(err error) string {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return "error: " + err.Error()
+	}
+	switch {
+	case hasReason(apiErr, "dailyLimitExceeded", "quotaExceeded") || apiErr.Code == http.StatusTooManyRequests:
+		return "quota exhausted"
+	case hasReason(apiErr, "keyInvalid") || strings.Contains(apiErr.Message, "API key not valid"):
+		return "invalid API key"
+	case apiErr.Code == http.StatusForbidden:
+		return "forbidden: " + apiErr.Message
+	case apiErr.Code == http.StatusBadRequest:
+		return "bad request (check the CSE ID): " + apiErr.Message
+	}
+	return fmt.Sprintf("HTTP %d: %s", apiErr.Code, apiErr.Message)
+}
+
+func checkEngine(ctx context.Context, key *APIKey) EngineStatus {
+	status := EngineStatus{Key: key.name, CSEID: key.cseID}
+	resp, err := key.svc.Cse.List().Cx(key.cseID).Q("test").Num(1).Context(ctx).Do()
+	if err != nil {
+		status.Status = describeEngineError(err)
+		return status
+	}
+
+	status.OK = true
+	status.Status = "ok"
+	var meta engineContext
+	if len(resp.Context) > 0 && json.Unmarshal(resp.Context, &meta) == nil {
+		status.Title = meta.Title
+		for _, group := range meta.Facets {
+			for _, facet := range group {
+				status.Facets = append(status.Facets, facet.Label)
+			}
+		}
+	}
+	return status
+}
+
+// listEngines issues one test query per key/CSE pair and prints a table of
+// the results. It reports whether every engine worked.
+func listEngines(ctx context.Context, pool *KeyPool, w io.Writer) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tCSE ID\tSTATUS\tENGINE\tFACETS")
+
+	allOK := true
+	for _, key := range pool.keys {
+		status := checkEngine(ctx, key)
+		allOK = allOK && status.OK
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", status.Key, status.CSEID, status.Status, status.Title, strings.Join(status.Facets, ", "))
+	}
+	tw.Flush()
+	return allOK
+}
+
+// warmup sends a single test query before a run so that network, auth or
+// quota problems stop it up front instead of failing every domain.
+func warmup(ctx context.Context, pool *KeyPool) error {
+	if len(pool.keys) == 0 {
+		return errors.New("no API keys configured")
+	}
+	status := checkEngine(ctx, pool.keys[0])
+	if !status.OK {
+		return fmt.Errorf("test query with key %s (CSE %s) failed: %s", status.Key, status.CSEID, status.Status)
+	}
+	logger.Debug("Warmup query succeeded with key %s", status.Key)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/option"
+)
+
+func TestListEngines(t *testing.T) {
+	setupLogger()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cx") == "exhausted" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403,"message":"Daily Limit Exceeded","errors":[{"reason":"dailyLimitExceeded"}]}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"context": map[string]interface{}{
+				"title":  "Scoped engine",
+				"facets": [][]map[string]string{{{"label": "docs", "anchor": "Docs"}}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	svc, err := customsearch.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := &KeyPool{slots: 1, keys: []*APIKey{
+		{svc: svc, cseID: "healthy", name: "AIza...0001", health: 1},
+		{svc: svc, cseID: "exhausted", name: "AIza...0002", health: 1},
+	}}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	var out strings.Builder
+	if listEngines(context.Background(), pool, &out) {
+		t.Error("listEngines reported success with an exhausted key")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 engines:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[1], "ok") || !strings.Contains(lines[1], "Scoped engine") || !strings.Contains(lines[1], "docs") {
+		t.Errorf("healthy engine line = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "quota exhausted") {
+		t.Errorf("exhausted engine line = %q", lines[2])
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	setupLogger()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("num"))
+		if r.URL.Query().Get("cx") == "exhausted" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403,"message":"Daily Limit Exceeded","errors":[{"reason":"dailyLimitExceeded"}]}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	svc, err := customsearch.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := &KeyPool{keys: []*APIKey{{svc: svc, cseID: "healthy", name: "key1"}}}
+	if err := warmup(context.Background(), ok); err != nil {
+		t.Errorf("warmup with a healthy key: %v", err)
+	}
+	exhausted := &KeyPool{keys: []*APIKey{{svc: svc, cseID: "exhausted", name: "key2"}}}
+	if err := warmup(context.Background(), exhausted); err == nil || !strings.Contains(err.Error(), "quota exhausted") {
+		t.Errorf("err = %v, want quota exhausted", err)
+	}
+	if len(requests) != 2 || requests[0] != "1" {
+		t.Errorf("requests = %v, want one single-result query per warmup", requests)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorKind categorizes why a domain's search failed, so callers can tell
+// a timeout from an exhausted quota or a bad key without parsing messages.
+type ErrorKind string
+
+const (
+	ErrorTimeout     ErrorKind = "timeout"
+	ErrorQuota       ErrorKind = "quota"
+	ErrorRateLimited ErrorKind = "rate_limited"
+	ErrorInvalidKey  ErrorKind = "invalid_key"
+	ErrorBadRequest  ErrorKind = "bad_request"
+	ErrorBackend     ErrorKind = "backend"
+	ErrorNetwork     ErrorKind = "network"
+	ErrorPanic       ErrorKind = "panic"
+	ErrorUnknown     ErrorKind = "unknown"
+)
+
+// classifyError maps an error from a search call to its ErrorKind.
+func classifyError(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return ErrorTimeout
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case dailyQuotaExceeded(apiErr):
+			return ErrorQuota
+		case apiErr.Code == http.StatusTooManyRequests || hasReason(apiErr, "rateLimitExceeded", "userRateLimitExceeded"):
+			return ErrorRateLimited
+		case apiErr.Code == http.StatusForbidden || hasReason(apiErr, "keyInvalid", "keyExpired") ||
+			strings.Contains(apiErr.Message, "API key not valid"):
+			return ErrorInvalidKey
+		case apiErr.Code >= http.StatusInternalServerError:
+			return ErrorBackend
+		case apiErr.Code == http.StatusBadRequest:
+			return ErrorBadRequest
+		}
+		return ErrorUnknown
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	return ErrorUnknown
+}
+
+// dailyQuotaExceeded reports whether apiErr means the key's daily quota is
+// spent. The live API reports that as a 429 rateLimitExceeded whose message
+// names the "Queries per day" limit, so the message is checked as well as
+// the reason.
+func dailyQuotaExceeded(apiErr *googleapi.Error) bool {
+	if hasReason(apiErr, "dailyLimitExceeded", "quotaExceeded") {
+		return true
+	}
+	message := strings.ToLower(apiErr.Message)
+	for _, item := range apiErr.Errors {
+		message += " " + strings.ToLower(item.Message)
+	}
+	return strings.Contains(message, "per day")
+}
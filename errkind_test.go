@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"deadline", fmt.Errorf("search: %w", context.DeadlineExceeded), ErrorTimeout},
+		{"daily quota", &googleapi.Error{Code: 429, Errors: []googleapi.ErrorItem{{Reason: "dailyLimitExceeded"}}}, ErrorQuota},
+		{"queries per day", &googleapi.Error{Code: 429, Message: dailyQuotaMessage, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, ErrorQuota},
+		{"rate limited", &googleapi.Error{Code: 429}, ErrorRateLimited},
+		{"invalid key", &googleapi.Error{Code: 400, Message: "API key not valid. Please pass a valid API key."}, ErrorInvalidKey},
+		{"bad request", &googleapi.Error{Code: 400, Message: "Invalid Value"}, ErrorBadRequest},
+		{"backend", &googleapi.Error{Code: 503}, ErrorBackend},
+		{"forbidden", &googleapi.Error{Code: 403}, ErrorInvalidKey},
+		{"not found", &googleapi.Error{Code: 404}, ErrorUnknown},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorNetwork},
+		{"other", errors.New("boom"), ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
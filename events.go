@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// eventLog is the -events stream. It is nil without the flag, and emitting
+// to it is then a no-op.
+var eventLog *eventStream
+
+// Event is one NDJSON line of the -events stream. Only the fields that
+// apply to an event are set.
+type Event struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Domain    string    `json:"domain,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	Start     int64     `json:"start,omitempty"`
+	Items     int       `json:"items,omitempty"`
+	Cached    bool      `json:"cached,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Targets   int       `json:"targets,omitempty"`
+	Results   int       `json:"results,omitempty"`
+	Failed    int       `json:"failed,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
+	// DurationSeconds is set on run_complete.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+type eventStream struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// openEvents opens the -events destination: stdout for "-", otherwise the
+// file path, appended to so a -watch loop or a restart keeps one timeline.
+func openEvents(dest string) (*eventStream, error) {
+	if dest == "-" {
+		return &eventStream{w: os.Stdout}, nil
+	}
+	file, err := openOutput(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return nil, err
+	}
+	return &eventStream{w: file}, nil
+}
+
+// emit writes event as one line. Passwords in URLs are masked, and with
+// -redact target domains are hashed the same way as in the log.
+func (s *eventStream) emit(event Event) {
+	if s == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	event.URL = redactFlagValue("", event.URL)
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Debug("Failed to encode %s event: %v", event.Event, err)
+		return
+	}
+	line := redactor.redact(string(data)) + "\n"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, line); err != nil {
+		logger.Debug("Failed to write %s event: %v", event.Event, err)
+	}
+}
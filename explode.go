@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// explodeResults writes every result to its own JSON file under dir, named
+// by a hash of its URL. Files are sharded into subdirectories by the first
+// two hex digits of the hash so no single directory grows unmanageably
+// large. The same URL found for several targets gets a numbered suffix
+// instead of overwriting the earlier file from this run.
+func explodeResults(dir string, results []Result) error {
+	written := make(map[string]bool, len(results))
+	for _, result := range results {
+		sum := sha256.Sum256([]byte(result.URL))
+		name := hex.EncodeToString(sum[:])
+		shard := filepath.Join(dir, name[:2])
+		if err := os.MkdirAll(shard, outputDirMode()); err != nil {
+			return err
+		}
+
+		path := filepath.Join(shard, name+".json")
+		for n := 1; written[path]; n++ {
+			path = filepath.Join(shard, fmt.Sprintf("%s-%d.json", name, n))
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(path, data); err != nil {
+			return err
+		}
+		written[path] = true
+	}
+	logger.Debug("Wrote %d result files to %s", len(written), dir)
+	return nil
+}
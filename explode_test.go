@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplodeResults(t *testing.T) {
+	setupLogger()
+	dir := t.TempDir()
+	results := []Result{
+		{URL: "https://a.example.com/", Domain: "example.com"},
+		{URL: "https://a.example.com/", Domain: "example.org"},
+		{URL: "https://b.example.com/", Domain: "example.com"},
+	}
+
+	if err := explodeResults(dir, results); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if len(files) != 3 {
+		t.Fatalf("wrote %d files, want 3: %v", len(files), files)
+	}
+	for _, file := range files {
+		shard, name := filepath.Split(file)
+		if !strings.HasPrefix(name, strings.TrimSuffix(shard, string(filepath.Separator))) {
+			t.Errorf("%s is not sharded by its hash prefix", file)
+		}
+	}
+}
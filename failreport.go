@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// writeFailedReport writes the -include-errors-in-exit-message list: one
+// line per failed domain with the error kind and message, sorted by domain.
+// A domain that failed for several dorks with the same error is listed once.
+func writeFailedReport(w io.Writer, failed []SearchResult) {
+	if len(failed) == 0 {
+		return
+	}
+	seen := make(map[string]bool, len(failed))
+	var lines []SearchResult
+	domains := make(map[string]bool)
+	for _, result := range failed {
+		if key := result.Domain + "\x00" + result.Error; !seen[key] {
+			seen[key] = true
+			lines = append(lines, result)
+		}
+		domains[result.Domain] = true
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Domain < lines[j].Domain })
+
+	fmt.Fprintf(w, "%d failed domains:\n", len(domains))
+	for _, result := range lines {
+		fmt.Fprintf(w, "  %s (%s): %s\n", result.Domain, result.ErrorKind, result.Error)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFailedReport(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, _ := newFailingCSE(t, 5, 1)
+	processDomains(context.Background(), []Target{
+		{Domain: "b.com", Query: "ext:sql"},
+		{Domain: "b.com", Query: "ext:env"},
+		{Domain: "a.com"},
+	}, pool)
+
+	var report strings.Builder
+	writeFailedReport(&report, progress.failedSearches())
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	if len(lines) != 3 || lines[0] != "2 failed domains:" {
+		t.Fatalf("report = %q, want a header and one line per domain", report.String())
+	}
+	for i, domain := range []string{"a.com", "b.com"} {
+		if !strings.HasPrefix(lines[i+1], "  "+domain+" (bad_request): Search failed: ") || !strings.Contains(lines[i+1], "bad page") {
+			t.Errorf("line %d = %q, want %s with its error", i+1, lines[i+1], domain)
+		}
+	}
+
+	report.Reset()
+	writeFailedReport(&report, nil)
+	if report.Len() != 0 {
+		t.Errorf("report without failures = %q, want nothing", report.String())
+	}
+}
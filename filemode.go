@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// outputMode is the permission for every file the tool writes, set from
+// -output-mode.
+var outputMode os.FileMode = 0644
+
+// parseOutputMode parses an octal permission such as "0600".
+func parseOutputMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q, expected octal permissions such as 0600", s)
+	}
+	if mode&0600 != 0600 {
+		return 0, fmt.Errorf("file mode %s must let the owner read and write", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// outputDirMode is outputMode for directories: searchable wherever it is
+// readable, so 0600 files live in 0700 directories.
+func outputDirMode() os.FileMode {
+	return outputMode | (outputMode&0444)>>2
+}
+
+// openOutput opens path with outputMode. The mode is also applied to a file
+// that already exists, since os.OpenFile only sets it on creation.
+func openOutput(path string, flags int) (*os.File, error) {
+	file, err := os.OpenFile(path, flags, outputMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(outputMode); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeOutput is os.WriteFile with outputMode.
+func writeOutput(path string, data []byte) error {
+	file, err := openOutput(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
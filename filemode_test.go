@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOutputMode(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0644", 0644, false},
+		{"600", 0600, false},
+		{"0640", 0640, false},
+		{"0888", 0, true},
+		{"01777", 0, true},
+		{"0444", 0, true},
+		{"rw", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseOutputMode(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOutputMode(%q) = %v, %v; want %v", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestWriteOutputMode(t *testing.T) {
+	outputMode = 0600
+	defer func() { outputMode = 0644 }()
+
+	path := filepath.Join(t.TempDir(), "results.txt")
+	os.WriteFile(path, []byte("old"), 0644)
+	if err := writeOutput(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if got := outputDirMode(); got != 0700 {
+		t.Errorf("dir mode = %v, want 0700", got)
+	}
+}
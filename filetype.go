@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var extensionRe = regexp.MustCompile(`^[a-z0-9]{1,5}$`)
+
+var mimeFileTypes = map[string]string{
+	"application/pdf":    "pdf",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.ms-excel": "xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         "xlsx",
+	"application/vnd.ms-powerpoint":                                             "ppt",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": "pptx",
+	"application/rtf": "rtf",
+	"text/plain":      "txt",
+	"text/html":       "html",
+}
+
+// officeFormats maps the file format descriptions Google gives Office
+// documents, which do not start with an extension like "PDF/Adobe Acrobat".
+var officeFormats = map[string]string{
+	"microsoft word":       "doc",
+	"microsoft excel":      "xls",
+	"microsoft powerpoint": "ppt",
+}
+
+// fileTypeOf names the type of a result, preferring the MIME type Google
+// reports, then its file format description, then the URL's extension.
+// Results with none of these are ordinary web pages.
+func fileTypeOf(mime, fileFormat, link string) string {
+	if fileType, ok := mimeFileTypes[strings.ToLower(mime)]; ok {
+		return fileType
+	}
+	if _, subtype, ok := strings.Cut(mime, "/"); ok && extensionRe.MatchString(strings.ToLower(subtype)) {
+		return strings.ToLower(subtype)
+	}
+	if fileType, ok := officeFormats[strings.ToLower(fileFormat)]; ok {
+		return fileType
+	}
+	if fields := strings.Fields(strings.SplitN(fileFormat, "/", 2)[0]); len(fields) > 0 {
+		if fileType := strings.ToLower(fields[0]); extensionRe.MatchString(fileType) {
+			return fileType
+		}
+	}
+	if u, err := url.Parse(link); err == nil {
+		if ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), ".")); extensionRe.MatchString(ext) {
+			return ext
+		}
+	}
+	return "html"
+}
+
+type fileTypeGroup struct {
+	FileType string
+	Results  []Result
+}
+
+func groupByFileType(results []Result) []fileTypeGroup {
+	index := make(map[string]int)
+	var groups []fileTypeGroup
+	for _, result := range results {
+		i, ok := index[result.FileType]
+		if !ok {
+			i = len(groups)
+			index[result.FileType] = i
+			groups = append(groups, fileTypeGroup{FileType: result.FileType})
+		}
+		groups[i].Results = append(groups[i].Results, result)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].FileType < groups[j].FileType })
+	return groups
+}
+
+// outputFileTypeGroups writes results in one bucket per file type.
+func outputFileTypeGroups(results []Result, out outputTarget) error {
+	groups := groupByFileType(results)
+	var output strings.Builder
+	switch out.format {
+	case "json":
+		byType := make(map[string][]Result, len(groups))
+		for _, group := range groups {
+			byType[group.FileType] = group.Results
+		}
+		data, err := marshalOutput(byType)
+		if err != nil {
+			return err
+		}
+		output.Write(data)
+		output.WriteString("\n")
+	case "csv":
+		writer := csv.NewWriter(&output)
+		writer.Write([]string{"FileType", "Domain", "Host", "Title", "URL", "Snippet", "CollectedAt"})
+		for _, group := range groups {
+			for _, result := range group.Results {
+				writer.Write([]string{group.FileType, result.Domain, result.Host, result.Title, result.URL, result.Snippet, formatCollectedAt(result.CollectedAt)})
+			}
+		}
+		writer.Flush()
+	default:
+		for _, group := range groups {
+			fmt.Fprintf(&output, "%s (%d):\n", group.FileType, len(group.Results))
+			for _, result := range group.Results {
+				fmt.Fprintf(&output, "%s\n%s\n", result.Title, result.URL)
+				if result.Snippet != "" {
+					output.WriteString(result.Snippet + "\n")
+				}
+				output.WriteString("\n")
+			}
+		}
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
+
+// parseFiletypes splits a -filetype list such as "pdf, .docx,xlsx".
+func parseFiletypes(list string) []string {
+	var types []string
+	for _, fileType := range strings.Split(list, ",") {
+		fileType = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), "."))
+		if fileType != "" {
+			types = append(types, fileType)
+		}
+	}
+	return types
+}
+
+func filetypeClause(types []string) string {
+	terms := make([]string, len(types))
+	for i, fileType := range types {
+		terms[i] = "filetype:" + fileType
+	}
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+func withClause(query, clause string) string {
+	return strings.TrimSpace(query + " " + clause)
+}
+
+// applyFiletypes restricts every target to the given file types. When the
+// OR list would push a query past maxLen characters, the types are split
+// across several searches of the same domain, whose results are merged.
+func applyFiletypes(targets []Target, types []string, maxLen int) ([]Target, error) {
+	var expanded []Target
+	for _, target := range targets {
+		var chunk []string
+		for _, fileType := range types {
+			next := append(chunk, fileType)
+			if len(constructQuery(target.Domain, withClause(target.Query, filetypeClause(next)))) <= maxLen {
+				chunk = next
+				continue
+			}
+			if len(chunk) == 0 {
+				return nil, fmt.Errorf("query for %s with filetype:%s is longer than %d characters", target.Domain, fileType, maxLen)
+			}
+			expanded = append(expanded, Target{Domain: target.Domain, Query: withClause(target.Query, filetypeClause(chunk)), Dork: target.Dork})
+			chunk = []string{fileType}
+			if len(constructQuery(target.Domain, withClause(target.Query, filetypeClause(chunk)))) > maxLen {
+				return nil, fmt.Errorf("query for %s with filetype:%s is longer than %d characters", target.Domain, fileType, maxLen)
+			}
+		}
+		if len(chunk) > 0 {
+			expanded = append(expanded, Target{Domain: target.Domain, Query: withClause(target.Query, filetypeClause(chunk)), Dork: target.Dork})
+		}
+	}
+	return expanded, nil
+}
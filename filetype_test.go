@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileTypeOf(t *testing.T) {
+	tests := []struct {
+		mime, fileFormat, link, want string
+	}{
+		{"application/pdf", "PDF/Adobe Acrobat", "https://example.com/report", "pdf"},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "", "https://example.com/x", "xlsx"},
+		{"", "Microsoft Word", "https://example.com/x", "doc"},
+		{"", "Unknown Format", "https://example.com/x", "html"},
+		{"", "PDF/Adobe Acrobat", "https://example.com/x", "pdf"},
+		{"", "", "https://example.com/dump.SQL?x=1", "sql"},
+		{"", "", "https://example.com/about/", "html"},
+		{"application/x-unknown-long-subtype", "", "https://example.com/a.log", "log"},
+	}
+	for _, tt := range tests {
+		if got := fileTypeOf(tt.mime, tt.fileFormat, tt.link); got != tt.want {
+			t.Errorf("fileTypeOf(%q, %q, %q) = %q, want %q", tt.mime, tt.fileFormat, tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestOutputFileTypeGroups(t *testing.T) {
+	out := outputTarget{format: "csv", path: filepath.Join(t.TempDir(), "out.csv")}
+	err := outputFileTypeGroups([]Result{
+		{Domain: "a.com", URL: "https://a.com/x.pdf", FileType: "pdf"},
+		{Domain: "a.com", URL: "https://a.com/", FileType: "html"},
+		{Domain: "b.com", URL: "https://b.com/y.pdf", FileType: "pdf"},
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(out.path)
+	want := "FileType,Domain,Host,Title,URL,Snippet,CollectedAt\n" +
+		"html,a.com,,,https://a.com/,,\n" +
+		"pdf,a.com,,,https://a.com/x.pdf,,\n" +
+		"pdf,b.com,,,https://b.com/y.pdf,,\n"
+	if string(got) != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyFiletypesSplitsLongLists(t *testing.T) {
+	var types []string
+	for i := 0; i < 30; i++ {
+		types = append(types, fmt.Sprintf("ext%02d", i))
+	}
+
+	targets, err := applyFiletypes([]Target{{Domain: "example.com", Query: "confidential"}}, types, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) < 2 {
+		t.Fatalf("got %d queries, want the list split", len(targets))
+	}
+
+	var covered []string
+	for _, target := range targets {
+		query := constructQuery(target.Domain, target.Query)
+		if len(query) > 256 {
+			t.Errorf("query is %d characters: %s", len(query), query)
+		}
+		if !strings.HasPrefix(target.Query, "confidential (filetype:") {
+			t.Errorf("query = %q, want the original query kept", target.Query)
+		}
+		for _, term := range strings.Fields(target.Query) {
+			if ext, ok := strings.CutPrefix(strings.Trim(term, "()"), "filetype:"); ok {
+				covered = append(covered, ext)
+			}
+		}
+	}
+	if strings.Join(covered, ",") != strings.Join(types, ",") {
+		t.Errorf("queries cover %v, want every type once", covered)
+	}
+
+	if _, err := applyFiletypes([]Target{{Domain: "example.com"}}, []string{"pdf"}, 10); err == nil {
+		t.Error("expected an error when a single file type cannot fit")
+	}
+}
+
+func TestParseFiletypes(t *testing.T) {
+	if got := strings.Join(parseFiletypes(" PDF, .docx,,xlsx "), ","); got != "pdf,docx,xlsx" {
+		t.Errorf("parseFiletypes = %s", got)
+	}
+	if got := filetypeClause([]string{"pdf"}); got != "filetype:pdf" {
+		t.Errorf("single clause = %s", got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// loadBaseline reads the first time each URL was seen from an earlier JSON
+// results file: a plain result list, -detailed-json, or either inside the
+// schema version 2 envelope. Results written before -first-seen existed fall
+// back to their collected_at time. A missing file is an empty baseline, so
+// the first run of a monitoring job needs no special case.
+func loadBaseline(path string) (map[string]time.Time, error) {
+	baseline := make(map[string]time.Time)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return baseline, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versioned struct {
+		SchemaVersion int             `json:"schema_version"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &versioned); err == nil && versioned.SchemaVersion >= 2 {
+		data = versioned.Data
+	}
+
+	// Each entry is either a result or a -detailed-json domain group.
+	var entries []struct {
+		Result
+		Results []Result `json:"results"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	var found []Result
+	for _, entry := range entries {
+		found = append(found, entry.Result)
+		found = append(found, entry.Results...)
+	}
+
+	for _, result := range found {
+		seen := result.CollectedAt
+		if result.FirstSeen != nil {
+			seen = *result.FirstSeen
+		}
+		if result.URL == "" || seen.IsZero() {
+			continue
+		}
+		if earliest, ok := baseline[result.URL]; !ok || seen.Before(earliest) {
+			baseline[result.URL] = seen
+		}
+	}
+	return baseline, nil
+}
+
+// stampFirstSeen sets FirstSeen on every result: the baseline's time for a
+// URL seen before, and runTime for a fresh find. Fresh finds are added to
+// baseline, so later -watch runs keep the time of the first one.
+func stampFirstSeen(found []Result, baseline map[string]time.Time, runTime time.Time) {
+	for i := range found {
+		seen, ok := baseline[found[i].URL]
+		if !ok || runTime.Before(seen) {
+			seen = runTime
+			baseline[found[i].URL] = seen
+		}
+		found[i].FirstSeen = &seen
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadBaseline(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.json")
+	os.WriteFile(plain, []byte(`[
+		{"url": "https://example.com/a.pdf", "domain": "example.com", "collected_at": "2026-03-01T00:00:00Z", "first_seen": "2026-01-01T00:00:00Z"},
+		{"url": "https://example.com/b.pdf", "domain": "example.com", "collected_at": "2026-03-01T00:00:00Z"}
+	]`), 0644)
+	detailed := filepath.Join(dir, "detailed.json")
+	os.WriteFile(detailed, []byte(`{"schema_version": 2, "data": [
+		{"domain": "example.com", "results": [
+			{"url": "https://example.com/a.pdf", "collected_at": "2026-02-01T00:00:00Z"}
+		]}
+	]}`), 0644)
+
+	baseline, err := loadBaseline(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"https://example.com/a.pdf": "2026-01-01T00:00:00Z",
+		"https://example.com/b.pdf": "2026-03-01T00:00:00Z",
+	}
+	if len(baseline) != len(want) {
+		t.Fatalf("baseline = %v, want %v", baseline, want)
+	}
+	for link, seen := range want {
+		if got := baseline[link].Format(time.RFC3339); got != seen {
+			t.Errorf("baseline[%q] = %s, want %s", link, got, seen)
+		}
+	}
+
+	baseline, err = loadBaseline(detailed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := baseline["https://example.com/a.pdf"].Format(time.RFC3339); got != "2026-02-01T00:00:00Z" {
+		t.Errorf("detailed baseline = %s, want 2026-02-01T00:00:00Z", got)
+	}
+
+	baseline, err = loadBaseline(filepath.Join(dir, "missing.json"))
+	if err != nil || len(baseline) != 0 {
+		t.Errorf("missing baseline = %v, %v, want empty", baseline, err)
+	}
+}
+
+func TestStampFirstSeen(t *testing.T) {
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	baseline := map[string]time.Time{"https://example.com/old": earlier}
+	found := []Result{{URL: "https://example.com/old"}, {URL: "https://example.com/new"}}
+
+	stampFirstSeen(found, baseline, run)
+	if !found[0].FirstSeen.Equal(earlier) {
+		t.Errorf("known URL first_seen = %v, want %v", found[0].FirstSeen, earlier)
+	}
+	if !found[1].FirstSeen.Equal(run) {
+		t.Errorf("new URL first_seen = %v, want %v", found[1].FirstSeen, run)
+	}
+
+	// A later -watch run keeps the time of the first find.
+	again := []Result{{URL: "https://example.com/new"}}
+	stampFirstSeen(again, baseline, run.Add(time.Hour))
+	if !again[0].FirstSeen.Equal(run) {
+		t.Errorf("second run first_seen = %v, want %v", again[0].FirstSeen, run)
+	}
+}
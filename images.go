@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/customsearch/v1"
+)
+
+// ImageMeta is what -images mode learns about an image result: the page it
+// appears on, its size, and any camera or location metadata Google has
+// indexed for it.
+type ImageMeta struct {
+	ContextURL string `json:"context_url,omitempty"`
+	Width      int64  `json:"width,omitempty"`
+	Height     int64  `json:"height,omitempty"`
+	Camera     string `json:"camera,omitempty"`
+	Software   string `json:"software,omitempty"`
+	TakenAt    string `json:"taken_at,omitempty"`
+	Latitude   string `json:"latitude,omitempty"`
+	Longitude  string `json:"longitude,omitempty"`
+}
+
+// Geotagged reports whether the image carries a location.
+func (m *ImageMeta) Geotagged() bool {
+	return m.Latitude != "" && m.Longitude != ""
+}
+
+// imageMetaOf collects image metadata from a search result. The pagemap is
+// loosely typed and differs between sites, so every object in it is scanned
+// for EXIF-style keys, ignoring namespaces such as "exif:" and values that
+// are not plain strings or numbers.
+func imageMetaOf(item *customsearch.Result) *ImageMeta {
+	meta := &ImageMeta{}
+	if item.Image != nil {
+		meta.ContextURL = item.Image.ContextLink
+		meta.Width, meta.Height = item.Image.Width, item.Image.Height
+	}
+
+	fields := make(map[string]string)
+	var pagemap map[string]json.RawMessage
+	if len(item.Pagemap) > 0 && json.Unmarshal(item.Pagemap, &pagemap) == nil {
+		for _, raw := range pagemap {
+			var objects []map[string]interface{}
+			if json.Unmarshal(raw, &objects) != nil {
+				continue
+			}
+			for _, object := range objects {
+				for key, value := range object {
+					name := strings.ToLower(key[strings.LastIndex(key, ":")+1:])
+					if _, seen := fields[name]; seen {
+						continue
+					}
+					switch v := value.(type) {
+					case string:
+						fields[name] = strings.TrimSpace(v)
+					case float64:
+						fields[name] = fmt.Sprint(v)
+					}
+				}
+			}
+		}
+	}
+
+	first := func(names ...string) string {
+		for _, name := range names {
+			if value := fields[name]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	meta.Camera = strings.TrimSpace(first("make") + " " + first("model"))
+	meta.Software = first("software")
+	meta.TakenAt = first("datetimeoriginal", "datetime", "datecreated")
+	meta.Latitude = first("gpslatitude", "latitude")
+	meta.Longitude = first("gpslongitude", "longitude")
+	if position := first("geo.position", "position"); position != "" && !meta.Geotagged() {
+		// geo.position meta tags hold "lat;long".
+		if lat, long, ok := strings.Cut(position, ";"); ok {
+			meta.Latitude, meta.Longitude = strings.TrimSpace(lat), strings.TrimSpace(long)
+		}
+	}
+	return meta
+}
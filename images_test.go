@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/customsearch/v1"
+)
+
+func TestImageMetaOf(t *testing.T) {
+	item := &customsearch.Result{
+		Image: &customsearch.ResultImage{ContextLink: "https://example.com/gallery", Width: 4000, Height: 3000},
+		Pagemap: []byte(`{
+			"metatags": [{"exif:make": "Canon", "exif:model": "EOS 80D", "exif:gpslatitude": "48.8584", "exif:gpslongitude": 2.2945, "og:image": {"nested": true}}],
+			"imageobject": [{"datetimeoriginal": "2023:06:01 10:00:00"}],
+			"cse_image": "not a list"
+		}`),
+	}
+	got := imageMetaOf(item)
+	want := ImageMeta{
+		ContextURL: "https://example.com/gallery", Width: 4000, Height: 3000,
+		Camera: "Canon EOS 80D", TakenAt: "2023:06:01 10:00:00", Latitude: "48.8584", Longitude: "2.2945",
+	}
+	if *got != want || !got.Geotagged() {
+		t.Errorf("imageMetaOf() = %+v, want %+v", *got, want)
+	}
+
+	position := imageMetaOf(&customsearch.Result{Pagemap: []byte(`{"metatags": [{"geo.position": "52.52; 13.40"}]}`)})
+	if position.Latitude != "52.52" || position.Longitude != "13.40" {
+		t.Errorf("geo.position = %+v", position)
+	}
+
+	if bare := imageMetaOf(&customsearch.Result{Pagemap: []byte(`[1, 2]`)}); *bare != (ImageMeta{}) {
+		t.Errorf("malformed pagemap = %+v, want empty", bare)
+	}
+}
+
+func TestImagesMode(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*imagesMode = true
+	defer func() { *imagesMode = false }()
+
+	pool, log := newFakeCSE(t, 2)
+	processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+	if got := log.all()[0].Get("searchType"); got != "image" {
+		t.Errorf("searchType = %q, want image", got)
+	}
+	for _, result := range collectedResults() {
+		if result.Image == nil {
+			t.Errorf("%s has no image metadata", result.URL)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightLimit caps the requests in flight at once across every phase of a
+// run: API searches and -probe-meta fetches alike, on top of -concurrent and
+// -probe-concurrency. It also bounds the workers each phase starts. A nil
+// limit never blocks.
+type inflightLimit chan struct{}
+
+// inflight is set from -max-inflight.
+var inflight inflightLimit
+
+func newInflightLimit(n int) inflightLimit {
+	if n <= 0 {
+		return nil
+	}
+	return make(inflightLimit, n)
+}
+
+// acquire blocks until a request may start or ctx is done. Each successful
+// acquire must be followed by release.
+func (l inflightLimit) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l inflightLimit) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// workers returns how many workers a phase that wants n may start: n, but
+// no more than the limit allows in flight at once, and at least one.
+func (l inflightLimit) workers(n int) int {
+	if l != nil && n > cap(l) {
+		n = cap(l)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// forEachBounded calls fn on every item from a fixed pool of at most
+// inflight.workers(workers) goroutines, so a list of 10,000 domains or hosts
+// never starts a goroutine per item.
+func forEachBounded[T any](items []T, workers int, fn func(T)) {
+	workers = inflight.workers(workers)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	jobs := make(chan T)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				fn(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/option"
+)
+
+func TestMaxInflightAcrossPhases(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pageDelay = 0
+	inflight = newInflightLimit(2)
+	defer func() { pageDelay, inflight = time.Second, nil }()
+
+	// One server answers both the searches and the -probe-meta fetches, and
+	// records the most requests it saw at once.
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/robots.txt" || r.URL.Path == "/sitemap.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": []map[string]string{{"link": "https://www.example.com/"}}})
+	}))
+	defer server.Close()
+
+	svc, err := customsearch.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := &KeyPool{slots: 6, keys: []*APIKey{{svc: svc, cseID: "cx", name: "test", health: 1}}}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	var targets []Target
+	var hosts []string
+	host := mustHost(t, server.URL)
+	for _, domain := range []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com"} {
+		targets = append(targets, Target{Domain: domain})
+		hosts = append(hosts, host)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		processDomains(context.Background(), targets, pool)
+	}()
+	go func() {
+		defer wg.Done()
+		probeHosts(context.Background(), &http.Client{Timeout: time.Second}, hosts, 6)
+	}()
+	wg.Wait()
+
+	if got := peak.Load(); got < 1 || got > 2 {
+		t.Errorf("peak requests in flight = %d, want at most 2", got)
+	}
+}
+
+func TestWorkersBounded(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pageDelay = 0
+	inflight = newInflightLimit(4)
+	defer func() { pageDelay, inflight = time.Second, nil }()
+
+	pool, _ := newFakeCSE(t, 1)
+	pool.slots = 4
+	var targets []Target
+	var hosts []string
+	for i := 0; i < 500; i++ {
+		targets = append(targets, Target{Domain: fmt.Sprintf("d%d.com", i)})
+		hosts = append(hosts, "127.0.0.1:1")
+	}
+
+	// Sample the goroutine count while each phase runs: one goroutine per
+	// domain or host would take it past 500.
+	baseline := runtime.NumGoroutine()
+	var peak atomic.Int32
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			if n := int32(runtime.NumGoroutine()); n > peak.Load() {
+				peak.Store(n)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	processDomains(context.Background(), targets, pool)
+	probeHosts(context.Background(), &http.Client{Timeout: time.Second}, hosts, 100)
+	close(done)
+	<-sampled
+
+	if got := int(peak.Load()) - baseline; got > 50 {
+		t.Errorf("%d goroutines above the baseline at peak, want a bounded worker pool", got)
+	}
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const (
+	minKeyHealth = 0.05
+
+	// cloudPlatformScope is requested for service account credentials, which
+	// the Custom Search API accepts in place of an API key.
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// APIKey is one API key paired with the search engine it queries.
+type APIKey struct {
+	svc      *customsearch.Service
+	cseID    string
+	name     string
+	health   float64
+	inflight int
+
+	// exhausted is set once the key runs out of daily quota. It is not
+	// handed out again until ResetHealth.
+	exhausted bool
+	queries   int
+	domains   map[string]bool
+	// issued counts the pages the key was handed out for, which -max-cost
+	// bills whether or not they succeed.
+	issued int
+}
+
+// freeDailyQueries is the Custom Search API's free tier: 100 queries per
+// key per day.
+const freeDailyQueries = 100
+
+// KeyUsage is how much of a run one key handled. EstimatedFreeLeft is the
+// free tier minus the queries this process made with the key; the API does
+// not report remaining quota, and queries from other runs today are not
+// counted.
+type KeyUsage struct {
+	Key               string `json:"key"`
+	CSEID             string `json:"cse_id"`
+	Queries           int    `json:"queries"`
+	Domains           int    `json:"domains"`
+	EstimatedFreeLeft int    `json:"estimated_free_left"`
+	Exhausted         bool   `json:"exhausted,omitempty"`
+}
+
+// errKeysExhausted is returned by AcquireEngine once every key it could
+// hand out has run out of daily quota.
+var errKeysExhausted = errors.New("every API key is out of daily quota")
+
+// KeyPool hands out API keys for individual requests. It holds a fixed
+// number of request slots and shares them between keys in proportion to
+// each key's health, so keys that keep failing carry less of the load.
+type KeyPool struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	keys  []*APIKey
+	slots int
+	used  int
+
+	// quotaHit records that a key ran out of daily quota since the last
+	// call to QuotaExhausted.
+	quotaHit bool
+	// costCapHit records that -max-cost stopped the run, so it is only
+	// logged once.
+	costCapHit bool
+
+	// waiters queues blocked Acquire calls so slots go to the highest
+	// priority first, and in arrival order within a priority.
+	waiters waitQueue
+	seq     uint64
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+}
+
+// waitQueue is a container/heap of waiters, highest priority on top.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "..." + key[len(key)-4:]
+}
+
+// NewKeyPool pairs API keys with CSE IDs by position, wrapping the shorter
+// list, and creates a search service for each pair. With -auth
+// serviceaccount there are no API keys: every CSE ID shares one service
+// authenticated with the credentials file or Application Default
+// Credentials.
+func NewKeyPool(ctx context.Context, config Config, slots int) (*KeyPool, error) {
+	if slots < 1 {
+		slots = 1
+	}
+	var transport http.RoundTripper
+	if customTransport() {
+		t, err := newAPITransport()
+		if err != nil {
+			return nil, err
+		}
+		transport = t
+	}
+	if *authMode == "serviceaccount" {
+		return newServiceAccountPool(ctx, config, slots, transport)
+	}
+
+	if len(config.GoogleAPI) != len(config.GoogleCSEID) && len(config.GoogleAPI) > 1 && len(config.GoogleCSEID) > 1 {
+		logger.Warn("Config has %d API keys but %d CSE IDs, pairing them by position", len(config.GoogleAPI), len(config.GoogleCSEID))
+	}
+
+	pairs := len(config.GoogleAPI)
+	if len(config.GoogleCSEID) > pairs {
+		pairs = len(config.GoogleCSEID)
+	}
+
+	pool := &KeyPool{slots: slots}
+	pool.cond = sync.NewCond(&pool.mu)
+	for i := 0; i < pairs; i++ {
+		apiKey := config.GoogleAPI[i%len(config.GoogleAPI)]
+		svc, err := newService(ctx, apiKeyOption(transport, apiKey))
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", maskKey(apiKey), err)
+		}
+		pool.keys = append(pool.keys, &APIKey{
+			svc:    svc,
+			cseID:  config.GoogleCSEID[i%len(config.GoogleCSEID)],
+			name:   maskKey(apiKey),
+			health: 1,
+		})
+	}
+	return pool, nil
+}
+
+func newServiceAccountPool(ctx context.Context, config Config, slots int, transport http.RoundTripper) (*KeyPool, error) {
+	opts := []option.ClientOption{option.WithScopes(cloudPlatformScope)}
+	name := "application-default"
+	if *credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(*credentialsFile))
+		name = "service-account"
+	}
+	if transport != nil {
+		client, err := serviceAccountClient(ctx, transport)
+		if err != nil {
+			return nil, fmt.Errorf("%s credentials: %v", name, err)
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(client)}
+	}
+	svc, err := newService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s credentials: %v", name, err)
+	}
+
+	pool := &KeyPool{slots: slots}
+	pool.cond = sync.NewCond(&pool.mu)
+	for _, cseID := range config.GoogleCSEID {
+		pool.keys = append(pool.keys, &APIKey{svc: svc, cseID: cseID, name: name, health: 1})
+	}
+	return pool, nil
+}
+
+// Service creation is retried this many times in total, starting at
+// serviceBackoff and doubling, before a network error is fatal.
+const serviceAttempts = 4
+
+var (
+	serviceBackoff   = time.Second
+	newSearchService = customsearch.NewService
+)
+
+// newService is customsearch.NewService retried with backoff on network
+// errors, so a DNS hiccup at startup does not end the run.
+func newService(ctx context.Context, opts ...option.ClientOption) (*customsearch.Service, error) {
+	backoff := serviceBackoff
+	for attempt := 1; ; attempt++ {
+		svc, err := newSearchService(ctx, opts...)
+		if err == nil || attempt == serviceAttempts {
+			return svc, err
+		}
+		if kind := classifyError(err); kind != ErrorNetwork && (kind != ErrorTimeout || ctx.Err() != nil) {
+			return nil, err
+		}
+		logger.Warn("Creating the search service failed (attempt %d/%d), retrying in %v: %v", attempt, serviceAttempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// limit is the number of slots a key may hold at once given the health of
+// every key in the pool. Each key keeps at least one slot so it can recover.
+func (p *KeyPool) limit(key *APIKey) int {
+	total := 0.0
+	for _, k := range p.keys {
+		total += math.Max(k.health, minKeyHealth)
+	}
+	share := float64(p.slots) * math.Max(key.health, minKeyHealth) / total
+	return int(math.Max(1, math.Round(share)))
+}
+
+// Acquire blocks until a slot is free and no higher-priority caller is
+// waiting, then returns the key with the most spare capacity. The key must
+// be handed back with Release.
+func (p *KeyPool) Acquire(ctx context.Context, priority int) (*APIKey, error) {
+	return p.AcquireEngine(ctx, priority, "")
+}
+
+// AcquireEngine is Acquire limited to the keys paired with the given CSE
+// ID. An empty cseID accepts any key.
+func (p *KeyPool) AcquireEngine(ctx context.Context, priority int, cseID string) (*APIKey, error) {
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	})
+	defer stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w := &waiter{priority: priority, seq: p.seq}
+	p.seq++
+	heap.Push(&p.waiters, w)
+	defer func() {
+		heap.Remove(&p.waiters, w.index)
+		// The next waiter may be able to take another free slot.
+		p.cond.Broadcast()
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var best *APIKey
+		bestSpare, usable, affordable := 0, false, false
+		for _, key := range p.keys {
+			if (cseID == "" || key.cseID == cseID) && !key.exhausted {
+				usable = true
+				affordable = affordable || !p.overCostCap(key)
+			}
+		}
+		if !usable {
+			return nil, errKeysExhausted
+		}
+		if !affordable {
+			if !p.costCapHit {
+				p.costCapHit = true
+				logger.Warn("Stopping: the next query would take the estimated spend past -max-cost $%.2f", *maxCost)
+			}
+			return nil, errCostCapReached
+		}
+		if p.used < p.slots && p.waiters[0] == w {
+			for _, key := range p.keys {
+				if (cseID != "" && key.cseID != cseID) || key.exhausted || p.overCostCap(key) {
+					continue
+				}
+				if spare := p.limit(key) - key.inflight; spare > bestSpare {
+					best, bestSpare = key, spare
+				}
+			}
+		}
+		if best != nil {
+			best.inflight++
+			best.issued++
+			p.used++
+			return best, nil
+		}
+		p.cond.Wait()
+	}
+}
+
+// keyFailure reports whether err says something about the key itself, such
+// as exhausted quota, rate limiting, a rejected key or a failing backend,
+// rather than about the query that was sent with it.
+func keyFailure(err error) bool {
+	if _, retryable := retryBackoff(err, 0); retryable {
+		return true
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusForbidden || hasReason(apiErr, "keyInvalid") || dailyQuotaExceeded(apiErr)
+}
+
+// Release returns a key's slot and updates its health from the outcome of
+// the request made with it.
+func (p *KeyPool) Release(key *APIKey, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key.inflight--
+	p.used--
+	switch {
+	case err == nil:
+		key.health += (1 - key.health) * 0.2
+	case keyFailure(err):
+		key.health /= 2
+		logger.Debug("Key %s health dropped to %.2f: %v", key.name, key.health, err)
+		if classifyError(err) == ErrorQuota {
+			p.quotaHit = true
+			if !key.exhausted {
+				key.exhausted = true
+				logger.Info("Key %s (CSE %s) is out of daily quota, moving its work to the other keys", key.name, key.cseID)
+			}
+		}
+	}
+	p.cond.Broadcast()
+}
+
+// Served records a successful query made with key for domain.
+func (p *KeyPool) Served(key *APIKey, domain string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key.queries++
+	if key.domains == nil {
+		key.domains = make(map[string]bool)
+	}
+	key.domains[domain] = true
+}
+
+// Usage reports what every key handled, in pool order.
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	usage := make([]KeyUsage, 0, len(p.keys))
+	for _, key := range p.keys {
+		left := max(0, freeDailyQueries-key.queries)
+		if key.exhausted {
+			left = 0
+		}
+		usage = append(usage, KeyUsage{
+			Key:               key.name,
+			CSEID:             key.cseID,
+			Queries:           key.queries,
+			Domains:           len(key.domains),
+			EstimatedFreeLeft: left,
+			Exhausted:         key.exhausted,
+		})
+	}
+	return usage
+}
+
+// QuotaExhausted reports whether any key ran out of daily quota since the
+// previous call.
+func (p *KeyPool) QuotaExhausted() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hit := p.quotaHit
+	p.quotaHit = false
+	return hit
+}
+
+// ResetHealth restores every key to full health, e.g. once daily quotas
+// have been reset.
+func (p *KeyPool) ResetHealth() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range p.keys {
+		key.health = 1
+		key.exhausted = false
+	}
+	p.cond.Broadcast()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var localeRe = regexp.MustCompile(`^([a-z]{2})(?:[-_]([a-z]{2}))?$`)
+
+// searchLocale holds the API parameters a -locale expands to: gl (country
+// to boost), hl (interface language) and lr (restrict results to language).
+type searchLocale struct {
+	gl, hl, lr string
+}
+
+// activeLocale is set from -locale; its fields are empty when unset.
+var activeLocale searchLocale
+
+// parseLocale expands a locale such as "de-DE" into its API parameters.
+// The region is optional. Chinese keeps it for hl and lr, since the API
+// distinguishes zh-CN from zh-TW.
+func parseLocale(locale string) (searchLocale, error) {
+	match := localeRe.FindStringSubmatch(strings.ToLower(locale))
+	if match == nil {
+		return searchLocale{}, fmt.Errorf("invalid locale %q, expected a language and optional region such as 'de-DE' or 'fr'", locale)
+	}
+	lang, region := match[1], match[2]
+
+	code := lang
+	if lang == "zh" && region != "" {
+		code = "zh-" + strings.ToUpper(region)
+	}
+	return searchLocale{gl: region, hl: code, lr: "lang_" + code}, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		locale  string
+		want    searchLocale
+		wantErr bool
+	}{
+		{"de-DE", searchLocale{gl: "de", hl: "de", lr: "lang_de"}, false},
+		{"pt_br", searchLocale{gl: "br", hl: "pt", lr: "lang_pt"}, false},
+		{"zh-TW", searchLocale{gl: "tw", hl: "zh-TW", lr: "lang_zh-TW"}, false},
+		{"fr", searchLocale{hl: "fr", lr: "lang_fr"}, false},
+		{"german", searchLocale{}, true},
+		{"de-", searchLocale{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLocale(tt.locale)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLocale(%q) = %+v, %v; want %+v", tt.locale, got, err, tt.want)
+		}
+	}
+}
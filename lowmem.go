@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// resultSink writes results to the output as they are found, for
+// -low-memory runs that must not hold every result until the end.
+type resultSink struct {
+	mu     sync.Mutex
+	file   *os.File // nil when writing to stdout
+	w      *bufio.Writer
+	csv    *csv.Writer
+	format string
+	err    error
+}
+
+// lowMemorySink is set while a -low-memory run streams its results.
+var lowMemorySink *resultSink
+
+// newResultSink opens -o, or stdout without it, and writes the header of
+// the single streaming format.
+func newResultSink(format string) (*resultSink, error) {
+	var out io.Writer = os.Stdout
+	sink := &resultSink{format: format}
+	if *outputArg != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *noOverwrite {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		}
+		file, err := openOutput(*outputArg, flags)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return nil, fmt.Errorf("refusing to overwrite existing file %s", *outputArg)
+			}
+			return nil, err
+		}
+		sink.file, out = file, file
+	}
+	sink.w = bufio.NewWriter(out)
+	if format == "csv" {
+		sink.csv = csv.NewWriter(sink.w)
+		header := resultsCSVHeader
+		if *urlsOnly {
+			header = []string{"Domain", "URL"}
+		}
+		sink.csv.Write(header)
+	}
+	return sink, nil
+}
+
+func (s *resultSink) write(result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+
+	switch {
+	case s.csv != nil && *urlsOnly:
+		s.csv.Write([]string{result.Domain, result.URL})
+	case s.csv != nil:
+		s.csv.Write(resultCSVRecord(result))
+	case *urlsOnly:
+		s.w.WriteString(result.URL + "\n")
+	default:
+		s.w.WriteString(formatResultTXT(result))
+	}
+	if s.csv != nil {
+		s.csv.Flush()
+		s.err = s.csv.Error()
+	}
+	// Flushing per result keeps the buffer, and so memory, bounded.
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.err != nil {
+		logger.Error("Failed to write result: %v", s.err)
+	}
+}
+
+// close flushes the output and reports the first write error.
+func (s *resultSink) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.file != nil {
+		if err := s.file.Close(); s.err == nil {
+			s.err = err
+		}
+	}
+	return s.err
+}
+
+// lowMemoryConflicts lists the options that need every result in memory
+// and so cannot be combined with -low-memory.
+func lowMemoryConflicts() []string {
+	var conflicts []string
+	add := func(set bool, name string) {
+		if set {
+			conflicts = append(conflicts, name)
+		}
+	}
+	add(*headLimit > 0, "-head")
+	add(*groupBy != "", "-group-by")
+	add(*explodeDir != "", "-explode-dir")
+	add(*splitFiletype != "", "-split-by-filetype")
+	add(*wordlistOut != "", "-wordlist-out")
+	add(*dedupeSnippet, "-dedupe-snippets")
+	add(*perDorkDir != "", "-output-per-dork")
+	add(*allCSE && !*subdomains, "-all-cse")
+	add(*deepPaginate && !*subdomains, "-deep-paginate")
+	add(*portsOnly, "-ports")
+	add(*snippetOnly, "-snippet-only")
+	add(*matchArg != "", "-match")
+	add(*firstSeen, "-first-seen")
+	add(*autoBucket, "-auto-bucket")
+	add(*encryptTo != "", "-encrypt-to")
+	add(*elasticURL != "" && !*subdomains, "-elasticsearch")
+	if !*subdomains {
+		formats := outputFormats()
+		add(len(formats) != 1 || (formats[0] != "csv" && formats[0] != "txt"), "-format other than a single csv or txt")
+		add(*bloomFile != "", "-bloom")
+		add(*watchInterval > 0, "-watch")
+		add(*appendOutput, "-append")
+		add(*webhookURL != "", "-webhook")
+	}
+	return conflicts
+}
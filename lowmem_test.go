@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLowMemoryStreamsResults(t *testing.T) {
+	setupLogger()
+	*lowMemory, *formatArg, *outputArg = true, "csv", filepath.Join(t.TempDir(), "results.csv")
+	defer func() { *lowMemory, *formatArg, *outputArg = false, "txt", "" }()
+
+	pool, _ := newFakeCSE(t, 3)
+	err := runSearch(context.Background(), pool, []Target{{Domain: "example.com"}, {Domain: "example.org"}}, runOptions{}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept := collectedResults(); len(kept) != 0 {
+		t.Errorf("%d results kept in memory", len(kept))
+	}
+
+	data, err := os.ReadFile(*outputArg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 7 || lines[0] != strings.Join(resultsCSVHeader, ",") {
+		t.Errorf("output =\n%s\nwant a header and 6 rows", data)
+	}
+}
+
+func TestLowMemoryConflicts(t *testing.T) {
+	*formatArg, *headLimit = "json", 5
+	defer func() { *formatArg, *headLimit = "txt", 0 }()
+	if got := strings.Join(lowMemoryConflicts(), ","); got != "-head,-format other than a single csv or txt" {
+		t.Errorf("conflicts = %s", got)
+	}
+
+	*subdomains = true
+	defer func() { *subdomains = false }()
+	if got := strings.Join(lowMemoryConflicts(), ","); got != "-head" {
+		t.Errorf("conflicts with -subs = %s", got)
+	}
+}
@@ -1,24 +1,34 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
 	"encoding/csv"
-	"encoding/json"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
 	"time"
 
 	"google.golang.org/api/customsearch/v1"
-	"google.golang.org/api/option"
 	"gopkg.in/yaml.v3"
 )
 
@@ -47,19 +57,28 @@ const (
 type Logger struct {
 	*log.Logger
 	level LogLevel
+	// warnings counts every Warn call, shown or not, for -strict.
+	warnings atomic.Int64
 }
 
 type Result struct {
-	Title      string   `json:"title"`
-	URL        string   `json:"url"`
-	Snippet    string   `json:"snippet"`
-	Domain     string   `json:"domain"`
-	Subdomains []string `json:"subdomains,omitempty"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Snippet     string     `json:"snippet"`
+	Domain      string     `json:"domain"`
+	Host        string     `json:"host,omitempty"`
+	Subdomains  []string   `json:"subdomains,omitempty"`
+	FileType    string     `json:"file_type,omitempty"`
+	CollectedAt time.Time  `json:"collected_at"`
+	FirstSeen   *time.Time `json:"first_seen,omitempty"`
+	Image       *ImageMeta `json:"image,omitempty"`
+	Dork        string     `json:"-"`
 }
 
 type Config struct {
-	GoogleAPI   []string `yaml:"Google-API"`
-	GoogleCSEID []string `yaml:"Google-CSE-ID"`
+	GoogleAPI   []string     `yaml:"Google-API"`
+	GoogleCSEID []string     `yaml:"Google-CSE-ID"`
+	Output      OutputConfig `yaml:"Output,omitempty"`
 }
 
 type SubdomainSet struct {
@@ -67,30 +86,145 @@ type SubdomainSet struct {
 	mu    sync.RWMutex
 }
 
+type Target struct {
+	Domain   string
+	Query    string
+	Priority int
+	Dork     string // the -dorks line the query was expanded from
+	CSEID    string // with -all-cse, the engine the query runs on
+	// DateRange is the -deep-paginate window as YYYYMMDD:YYYYMMDD, e.g.
+	// "20260915:20261014".
+	DateRange string
+}
+
 type SearchResult struct {
-	Domain     string   `json:"domain"`
-	Subdomains []string `json:"subdomains"`
-	Error      string   `json:"error,omitempty"`
+	Domain     string     `json:"domain"`
+	Queries    []string   `json:"queries,omitempty"`
+	Subdomains []string   `json:"subdomains,omitempty"`
+	Results    []Result   `json:"results,omitempty"`
+	Count      int        `json:"count,omitempty"`
+	Truncated  bool       `json:"truncated,omitempty"`
+	Partial    bool       `json:"partial,omitempty"`
+	Flagged    bool       `json:"flagged,omitempty"`
+	Meta       []HostMeta `json:"meta,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorKind  ErrorKind  `json:"error_kind,omitempty"`
 }
 
 var (
-	queryArg     = flag.String("q", "", "Google dorking query for your target")
-	domainArg    = flag.String("d", "", "Target name for Google dorking")
-	outputArg    = flag.String("o", "", "File name to save the dorking results")
-	formatArg    = flag.String("format", "txt", "Output format (txt, json, csv)")
-	subdomains   = flag.Bool("subs", false, "Only output found subdomains")
-	concurrent   = flag.Int("concurrent", 10, "Number of concurrent searches")
-	verbosity    = flag.Int("v", 1, "Verbosity level (0=ERROR, 1=INFO, 2=DEBUG, 3=TRACE)")
-	showVersion  = flag.Bool("version", false, "Show version information")
-	noColor      = flag.Bool("no-color", false, "Disable color output")
-	silent       = flag.Bool("silent", false, "Silent mode - only output results")
-	timeout      = flag.Duration("timeout", 5*time.Minute, "Timeout for the entire search operation")
-	results      []Result
-	resultsMutex sync.Mutex
-	subdomainSet = NewSubdomainSet()
-	logger       *Logger
+	queryArg        = flag.String("q", "", "Google dorking query for your target")
+	domainArg       = flag.String("d", "", "Target name for Google dorking")
+	domainList      = flag.String("dL", "", "File containing target domains, one per line (optionally 'domain | query')")
+	outputArg       = flag.String("o", "", "File name to save the dorking results")
+	noOverwrite     = flag.Bool("no-overwrite", false, "Refuse to write output if the -o file already exists")
+	formatArg       = flag.String("format", "txt", "Output format (txt, json, csv, urllist, template), or a comma-separated list to write several with -o")
+	subdomains      = flag.Bool("subs", false, "Only output found subdomains")
+	flatSubs        = flag.Bool("flat-subs", false, "With -subs, print one sorted list of every domain's subdomains without duplicates or domain headers")
+	concurrent      = flag.Int("concurrent", 10, "Number of concurrent searches")
+	verbosity       = flag.Int("v", 1, "Verbosity level (0=ERROR, 1=INFO, 2=DEBUG, 3=TRACE)")
+	showVersion     = flag.Bool("version", false, "Show version information")
+	noColor         = flag.Bool("no-color", false, "Disable color output")
+	silent          = flag.Bool("silent", false, "Silent mode - only output results")
+	timeout         = flag.Duration("timeout", 5*time.Minute, "Timeout for the entire search operation")
+	maxCost         = flag.Float64("max-cost", 0, "Stop before the estimated spend beyond the free 100 queries per key passes this many dollars, at $5 per 1000 queries (0 for no cap)")
+	strict          = flag.Bool("strict", false, "Exit with an error if anything was warned about or any domain failed, e.g. for CI")
+	retry429Only    = flag.Bool("retry-429-only", false, "Only retry rate-limited requests; fail immediately on backend and network errors")
+	throttle429     = flag.Duration("throttle-on-429", 0, "Pause all workers for this long whenever any request is rate limited, e.g. 10s")
+	redact          = flag.Bool("redact", false, "Replace target domain names in log output with stable hashes")
+	bloomFile       = flag.String("bloom", "", "Bloom filter file of previously seen items; only unseen items are output")
+	bloomSize       = flag.Uint64("bloom-size", 1<<24, "Number of bits in a new bloom filter")
+	bloomHashes     = flag.Uint("bloom-hashes", 7, "Number of hash functions in a new bloom filter")
+	schemaVersion   = flag.Int("output-schema-version", 1, "JSON output layout: 1 is the original document, 2 wraps it with a schema_version field")
+	detailedJSON    = flag.Bool("detailed-json", false, "Write JSON as per-domain objects with metadata such as truncation")
+	explodeDir      = flag.String("explode-dir", "", "Write each result as its own JSON file under this directory")
+	splitFiletype   = flag.String("split-by-filetype", "", "Also write the result URLs of each file type to their own file under this directory, e.g. pdf.txt and xlsx.txt")
+	quietProgress   = flag.Bool("quiet-progress", false, "Only log progress milestones instead of every domain and result")
+	progressEvery   = flag.Int("progress-every", 0, "With -quiet-progress, log every N finished domains (default every 10%)")
+	urlsOnly        = flag.Bool("urls", false, "Only output result URLs, skipping subdomain extraction")
+	snippetOnly     = flag.Bool("snippet-only", false, "Only output the distinct snippet text of results, one per line")
+	denylistFile    = flag.String("url-denylist", "", "File of result URLs to suppress, one exact URL or glob pattern (* and ?) per line")
+	matchArg        = flag.String("match", "", "Only keep results whose snippet matches this regexp, e.g. 'AIza[0-9A-Za-z_-]{35}'")
+	portsOnly       = flag.Bool("ports", false, "Only output host:port of results on non-standard ports, e.g. :8443")
+	includeOmitted  = flag.Bool("include-omitted", false, "Include results Google omits as very similar (API filter=0)")
+	listEnginesArg  = flag.Bool("list-engines", false, "Test every configured API key/CSE ID pair and exit")
+	limitDomains    = flag.Int("limit-domains", 0, "Only search the first N domains after loading them, e.g. to try a config on a large list (0 for all)")
+	shuffle         = flag.Bool("shuffle", false, "Process domains in random order instead of input order")
+	printConfig     = flag.Bool("print-config", false, "Print the effective configuration with API keys masked and exit")
+	appendOutput    = flag.Bool("append", false, "Add to the existing -o file: -subs output is merged and kept sorted, CSV results are appended")
+	headLimit       = flag.Int("head", 0, "Stop after the first N results across all domains, fetching one page per domain")
+	dorkFile        = flag.String("dorks", "", "File of dorks, one per line, run against every domain; supports {{domain}}, {{year}} and -var macros")
+	wordlistOut     = flag.String("wordlist-out", "", "Write a sorted, deduplicated wordlist of URL path segments and parameter names to this file")
+	wordlistMinLen  = flag.Int("wordlist-min-len", 3, "Minimum length of a -wordlist-out token")
+	wordlistChars   = flag.String("wordlist-charset", "a-zA-Z0-9_.-", "Characters allowed in a -wordlist-out token, as a regexp character class")
+	compareArg      = flag.Bool("compare-engines", false, "Run each query on every distinct CSE ID and report shared and engine-only results")
+	onlyWithSubs    = flag.Bool("only-with-subdomains", false, "With -subs, leave out domains that had no subdomains")
+	probeMeta       = flag.Bool("probe-meta", false, "With -subs, fetch robots.txt and sitemap.xml from each subdomain and report disallowed paths and sitemap URLs")
+	probeWorkers    = flag.Int("probe-concurrency", 10, "Number of hosts -probe-meta fetches from at once")
+	probeTimeout    = flag.Duration("probe-timeout", 10*time.Second, "Timeout for each -probe-meta request")
+	maxInflight     = flag.Int("max-inflight", 0, "Cap on requests and workers in flight at once across searches and -probe-meta combined (0 for the -concurrent value)")
+	webhookURL      = flag.String("webhook", "", "POST a JSON report of the run to this URL when it finishes")
+	webhookTmpl     = flag.String("webhook-template", "", "Go template file used to render the -webhook body instead of the default JSON")
+	elasticURL      = flag.String("elasticsearch", "", "Index results into this Elasticsearch index URL, e.g. http://localhost:9200/dorks")
+	highlightMin    = flag.Int("highlight-threshold", 0, "Flag domains with more than this many results for priority review")
+	groupBy         = flag.String("group-by", "", "Group result output into buckets; the only grouping is 'filetype'")
+	authMode        = flag.String("auth", "apikey", "How to authenticate: 'apikey' uses the config keys, 'serviceaccount' uses -credentials or Application Default Credentials")
+	credentialsFile = flag.String("credentials", "", "Service account JSON file for -auth serviceaccount (default: Application Default Credentials)")
+	watchInterval   = flag.Duration("watch", 0, "Re-run the search at this interval, outputting only new findings, until interrupted")
+	filetypeArg     = flag.String("filetype", "", "Comma-separated file types to search for, e.g. pdf,docx,xlsx")
+	maxQueryLen     = flag.Int("max-query-length", 256, "Split -filetype lists across several queries to keep each query at most this long")
+	priorityFile    = flag.String("priority-file", "", "File of 'domain priority' lines; higher-priority domains get API requests first")
+	cacheDir        = flag.String("cache-dir", "", "Cache API responses in this directory and reuse them instead of spending quota")
+	cacheTTL        = flag.Duration("cache-ttl", 24*time.Hour, "How long cached API responses stay fresh")
+	dryRun          = flag.Bool("dry-run", false, "Show the queries that would run, and what -cache-dir would serve, without calling the API")
+	warmupArg       = flag.Bool("warmup", false, "Send one test query before the run and abort if it fails")
+	fileTypeParam   = flag.String("file-type", "", "Restrict results to one file type with the API fileType parameter, e.g. pdf")
+	siteSearch      = flag.String("site-search", "", "Also restrict every query to this site with the API siteSearch parameter, on top of the site: in the query")
+	siteFilter      = flag.String("site-filter", "", "Whether -site-search includes (i) or excludes (e) the site (API siteSearchFilter; default i)")
+	linksTo         = flag.String("links-to", "", "Only return pages that link to this URL, for backlink discovery (API linkSite parameter)")
+	domainSummaryFD = flag.Int("domain-summary-fd", 0, "Write one 'DOMAIN name results=N ...' line per finished domain to this file descriptor (1 for stdout)")
+	totalPerDomain  = flag.Int("total-per-domain", apiResultCeiling, "Fetch at most this many results per query to save quota (at most 100)")
+	pagesArg        = flag.Int("pages", 10, "Fetch at most this many result pages per query; the API serves no more than 100 results")
+	perPageArg      = flag.Int("per-page", 10, "Results to request per page (1-10)")
+	proxyArg        = flag.String("proxy", "", "Send API requests through this HTTP proxy, e.g. http://127.0.0.1:8080")
+	caCertFile      = flag.String("ca-cert", "", "PEM file of extra CA certificates to trust for API requests, e.g. a proxy's CA")
+	insecureTLS     = flag.Bool("insecure-skip-verify", false, "Do not verify TLS certificates of API requests (debugging through a proxy only)")
+	jsonlDir        = flag.String("output-jsonl-per-domain", "", "Stream each result to <dir>/<domain>.jsonl as it is found")
+	localeArg       = flag.String("locale", "", "Search as a locale such as de-DE: sets the country (gl), interface language (hl) and result language (lr)")
+	outputModeArg   = flag.String("output-mode", "0644", "Octal permissions for written files, e.g. 0600 for sensitive findings")
+	lowMemory       = flag.Bool("low-memory", false, "Do not keep results in memory: results are written as they are found (csv or txt only)")
+	imagesMode      = flag.Bool("images", false, "Run image searches and report image metadata such as camera and geotags")
+	c2coff          = flag.Bool("c2coff", false, "Turn off Simplified and Traditional Chinese search (API c2coff=1)")
+	dedupeSnippet   = flag.Bool("dedupe-snippets", false, "Collapse results of a domain whose title and snippet match an earlier result, keeping the first")
+	perDorkDir      = flag.String("output-per-dork", "", "Also write each dork's results, across all domains, to <dir>/<dork>.<format>")
+	normalizeURLs   = flag.Bool("normalize-urls", false, "Canonicalize result URLs: lowercase host, no default port, no #fragment")
+	stripSlash      = flag.Bool("strip-trailing-slash", false, "With -normalize-urls, also remove trailing slashes from URL paths")
+	collapseWWW     = flag.Bool("collapse-www", false, "Treat www.x.example.com and x.example.com as one subdomain, keeping x.example.com")
+	minDepth        = flag.Int("min-depth", 0, "With -subs, drop subdomains with fewer than this many labels beyond the registrable domain")
+	deepPaginate    = flag.Bool("deep-paginate", false, "Also run every query in each of the past 12 months and merge the results, to get past the 100-result cap; costs up to 13 times the quota")
+	allCSE          = flag.Bool("all-cse", false, "Run every query on each configured CSE ID and merge the results, at a higher quota cost")
+	emitCmd         = flag.String("emit-command", "", "Write the command line that reproduces this run, with secrets redacted, to this file or '-' for stderr")
+	profileKind     = flag.String("profile", "", "Write a pprof profile of the run: cpu or mem")
+	profileOut      = flag.String("profile-out", "", "File for the -profile output (default cpu.pprof or mem.pprof)")
+	verboseTiming   = flag.Bool("verbose-timing", false, "Log the latency of every API page fetch and a rolling average at debug level (-v 2)")
+	firstSeen       = flag.Bool("first-seen", false, "Add a first_seen time to each JSON result: the run time for new URLs, or the earlier time from -baseline")
+	baselineFile    = flag.String("baseline", "", "Earlier JSON results output whose first_seen times are kept by -first-seen, e.g. the previous run's -o file")
+	autoBucket      = flag.Bool("auto-bucket", false, "Run the -q query once without site:, e.g. on a CSE already scoped to your domains, and group results by registrable domain")
+	templateFile    = flag.String("template-file", "", "Go template file that renders the output for -format template")
+	failedReport    = flag.Bool("include-errors-in-exit-message", false, "End the run with a list of the failed domains and why they failed on stderr (not with -silent)")
+	maxEmptyPages   = flag.Int("max-empty-pages", 0, "Stop paging a query after this many pages in a row left no results, e.g. all on -url-denylist (0 to page on)")
+	encryptTo       = flag.String("encrypt-to", "", "Encrypt the -o file to comma-separated age recipients (age1...), or to the age recipients or PGP public key in this file")
+	eventsDest      = flag.String("events", "", "Append NDJSON lifecycle events (run, domain, page and result) to this file, or '-' for stdout")
+	summaryFD       = flag.Int("summary-fd", 0, "Write a JSON run summary to this file descriptor (e.g. 3)")
+	pauseFile       = flag.String("pause-file", "", "Pause new requests while this file exists")
+	geoArg          = flag.String("geo", "", "Two-letter country code to boost results from (API gl parameter)")
+	results         []Result
+	subdomainSet    = NewSubdomainSet()
+	logger          *Logger
+	redactor        *redactingWriter
 )
 
+var countryCodeRe = regexp.MustCompile(`^[a-z]{2}$`)
+
 var (
 	colorReset  = "\033[0m"
 	colorRed    = "\033[31m"
@@ -130,6 +264,13 @@ func (l *Logger) Error(format string, v ...interface{}) {
 	}
 }
 
+func (l *Logger) Warn(format string, v ...interface{}) {
+	l.warnings.Add(1)
+	if l.level >= INFO && !*silent {
+		l.Printf("%s[WARN]%s "+format, append([]interface{}{colorYellow, colorReset}, v...)...)
+	}
+}
+
 func (l *Logger) Info(format string, v ...interface{}) {
 	if l.level >= INFO && !*silent {
 		l.Printf("%s[INFO]%s "+format, append([]interface{}{colorBlue, colorReset}, v...)...)
@@ -159,7 +300,10 @@ func init() {
 		fmt.Println("  google-dorker -d example.com -subs -format json")
 		fmt.Println("  google-dorker -d example.com -subs -silent")
 		fmt.Println("  google-dorker -d example.com -concurrent 20 -format csv -o results.csv")
-		fmt.Println("  google-dorker -d example.com sub1.example.com sub2.example.com -subs\n")
+		fmt.Println("  google-dorker -dL domains.txt -q \"inurl:admin\" -subs")
+		fmt.Println("  google-dorker -list-engines")
+		fmt.Println("  google-dorker -d example.com sub1.example.com sub2.example.com -subs")
+		fmt.Println()
 	}
 }
 
@@ -180,11 +324,91 @@ func setupLogger() {
 	}
 }
 
-func loadConfig() string {
+type redactingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	domains []string
+	pattern *regexp.Regexp
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	line := string(p)
+	if r.pattern != nil {
+		line = r.pattern.ReplaceAllStringFunc(line, redactDomain)
+	}
+	if _, err := io.WriteString(r.w, line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redact hides the registered domains in s, for output such as -events that
+// does not go through the log. It is a no-op on a nil writer.
+func (r *redactingWriter) redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pattern != nil {
+		s = r.pattern.ReplaceAllStringFunc(s, redactDomain)
+	}
+	return s
+}
+
+// add starts redacting domain, matching it case-insensitively and trying
+// longer domains first so one never partially masks another containing it.
+func (r *redactingWriter) add(domain string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return
+	}
+	for _, d := range r.domains {
+		if d == domain {
+			return
+		}
+	}
+	r.domains = append(r.domains, domain)
+	sort.Slice(r.domains, func(i, j int) bool { return len(r.domains[i]) > len(r.domains[j]) })
+
+	quoted := make([]string, len(r.domains))
+	for i, d := range r.domains {
+		quoted[i] = regexp.QuoteMeta(d)
+	}
+	r.pattern = regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+func redactDomain(domain string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(domain)))
+	return "domain#" + hex.EncodeToString(sum[:2])
+}
+
+// enableRedaction routes log output through a writer that hides every
+// domain passed to redactTarget. It must run before targets are parsed so
+// no log line mentions a domain before it is registered.
+func enableRedaction() {
+	redactor = &redactingWriter{w: os.Stderr}
+	logger.SetOutput(redactor)
+}
+
+func redactTarget(domain string) {
+	if redactor != nil {
+		redactor.add(domain)
+	}
+}
+
+// findConfigFile returns the first config file that exists, or "", and
+// every location it checked.
+func findConfigFile() (string, []string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		logger.Error("Failed to get home directory: %v", err)
-		os.Exit(1)
+		return "", nil, err
 	}
 
 	configLocations := []string{
@@ -192,15 +416,25 @@ func loadConfig() string {
 		filepath.Join(homeDir, ".config/google_dorker.yaml"),
 		"/etc/google_dorker.yaml",
 	}
-
-	var configPath string
 	for _, loc := range configLocations {
 		if _, err := os.Stat(loc); err == nil {
-			configPath = loc
-			break
+			return loc, configLocations, nil
 		}
 	}
+	return "", configLocations, nil
+}
+
+func loadConfig() string {
+	configPath, configLocations, err := findConfigFile()
+	if err != nil {
+		logger.Error("Failed to get home directory: %v", err)
+		os.Exit(1)
+	}
 
+	if configPath == "" && hasCredentials(credentialOverrides()) {
+		logger.Debug("No config file found, using credentials from %s and %s", envAPIKey, envCSEID)
+		return ""
+	}
 	if configPath == "" {
 		logger.Error("Config file not found. Checked locations:")
 		for _, loc := range configLocations {
@@ -219,27 +453,118 @@ func loadConfig() string {
 	return absPath
 }
 
-func loadAPIConfig(filename string) Config {
-	configFile, err := ioutil.ReadFile(filename)
-	if err != nil {
-		logger.Error("Failed to read config file: %v", err)
-		os.Exit(1)
+// Environment variables that supply credentials without a config file, each
+// a comma-separated list. They take precedence over the config file.
+const (
+	envAPIKey = "GOOGLE_API_KEY"
+	envCSEID  = "GOOGLE_CSE_ID"
+)
+
+func envConfig() Config {
+	split := func(value string) []string {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
 	}
+	return Config{GoogleAPI: split(os.Getenv(envAPIKey)), GoogleCSEID: split(os.Getenv(envCSEID))}
+}
 
+// hasCredentials reports whether config holds everything -auth needs.
+func hasCredentials(config Config) bool {
+	return len(config.GoogleCSEID) > 0 && (len(config.GoogleAPI) > 0 || *authMode == "serviceaccount")
+}
+
+// loadAPIConfig reads the config file, if any, and applies credentials from
+// the environment, or returns those from the command line alone. A config
+// file that cannot be read or parsed is fatal unless the environment holds
+// complete credentials, in which case it is only a warning.
+func loadAPIConfig(filename string) Config {
+	env := credentialOverrides()
 	var config Config
-	if err := yaml.Unmarshal(configFile, &config); err != nil {
-		logger.Error("Failed to parse config file: %v", err)
-		os.Exit(1)
+	if filename != "" {
+		var msg string
+		configFile, err := ioutil.ReadFile(filename)
+		if err != nil {
+			msg = "Failed to read config file"
+		} else if config, err = parseConfig(configFile); err != nil {
+			msg = "Failed to parse config file"
+		}
+		if err != nil && !hasCredentials(env) {
+			logger.Error("%s: %v", msg, err)
+			os.Exit(1)
+		}
+		if err != nil {
+			logger.Warn("%s: %v; continuing with credentials from %s and %s", msg, err, envAPIKey, envCSEID)
+			config = Config{}
+		}
+	}
+	if len(env.GoogleAPI) > 0 {
+		config.GoogleAPI = env.GoogleAPI
+	}
+	if len(env.GoogleCSEID) > 0 {
+		config.GoogleCSEID = env.GoogleCSEID
 	}
 
-	if len(config.GoogleAPI) == 0 || len(config.GoogleCSEID) == 0 {
-		logger.Error("Google API key or CSE ID missing from config")
+	if len(config.GoogleCSEID) == 0 {
+		logger.Error("Google CSE ID missing from config")
+		os.Exit(1)
+	}
+	if len(config.GoogleAPI) == 0 && *authMode == "apikey" {
+		logger.Error("Google API key missing from config (or use -auth serviceaccount)")
 		os.Exit(1)
 	}
 
 	return config
 }
 
+// parseConfig decodes the YAML config. Unlike a plain yaml.Unmarshal, which
+// rejects the whole file over a repeated top-level key, it warns about each
+// duplicate and merges list values so no credentials are lost.
+func parseConfig(data []byte) (Config, error) {
+	var config Config
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return config, err
+	}
+	if len(doc.Content) == 0 {
+		return config, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind == yaml.MappingNode {
+		mergeDuplicateKeys(root)
+	}
+	err := root.Decode(&config)
+	return config, err
+}
+
+func mergeDuplicateKeys(mapping *yaml.Node) {
+	first := make(map[string]*yaml.Node)
+	content := mapping.Content[:0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		prev, seen := first[key.Value]
+		if !seen {
+			first[key.Value] = value
+			content = append(content, key, value)
+			continue
+		}
+
+		if prev.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode {
+			logger.Warn("Config key %q is defined more than once (line %d), merging its values", key.Value, key.Line)
+			prev.Content = append(prev.Content, value.Content...)
+		} else {
+			logger.Warn("Config key %q is defined more than once (line %d), using the last value", key.Value, key.Line)
+			*prev = *value
+		}
+	}
+	mapping.Content = content
+}
+
 func extractSubdomains(domain, urlStr string) []string {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -247,16 +572,20 @@ func extractSubdomains(domain, urlStr string) []string {
 		return nil
 	}
 
-	host := parsedURL.Hostname()
-	if !strings.HasSuffix(host, domain) {
+	host := strings.ToLower(parsedURL.Hostname())
+	suffix := "." + strings.ToLower(domain)
+	if !strings.HasSuffix(host, suffix) {
 		return nil
 	}
-
-	if host != domain {
-		subdomainSet.Add(host)
-		logger.Debug("Found subdomain: %s", host)
+	// -collapse-www folds www.x.example.com into x.example.com, but keeps
+	// www.example.com since the bare domain is not a subdomain.
+	if trimmed := strings.TrimPrefix(host, "www."); *collapseWWW && strings.HasSuffix(trimmed, suffix) {
+		host = trimmed
 	}
-	return subdomainSet.ToSlice()
+
+	subdomainSet.Add(host)
+	logger.Debug("Found subdomain: %s", host)
+	return []string{host}
 }
 
 func constructQuery(domain, query string) string {
@@ -268,228 +597,1741 @@ func constructQuery(domain, query string) string {
 	return fmt.Sprintf("site:%s", domain)
 }
 
-func performSearch(ctx context.Context, svc *customsearch.Service, cseID, query string, domain string, results chan<- SearchResult) {
+func newListCall(svc *customsearch.Service, cseID, query string, start, num int64) *customsearch.CseListCall {
+	req := svc.Cse.List().Cx(cseID).Q(query).Num(num).Start(start)
+	if *geoArg != "" {
+		req.Gl(*geoArg)
+	}
+	if *includeOmitted {
+		req.Filter("0")
+	}
+	if *imagesMode {
+		req.SearchType("image")
+	}
+	if *c2coff {
+		req.C2coff("1")
+	}
+	if activeLocale.hl != "" {
+		req.Hl(activeLocale.hl).Lr(activeLocale.lr)
+	}
+	if *fileTypeParam != "" {
+		req.FileType(*fileTypeParam)
+	}
+	if *siteSearch != "" {
+		req.SiteSearch(*siteSearch)
+		if *siteFilter != "" {
+			req.SiteSearchFilter(*siteFilter)
+		}
+	}
+	if *linksTo != "" {
+		req.LinkSite(*linksTo)
+	}
+	return req
+}
+
+func performSearch(ctx context.Context, pool *KeyPool, target Target, results chan<- SearchResult) {
+	query, domain, priority := constructQuery(target.Domain, target.Query), target.Domain, target.Priority
+	dork := target.Dork
+	if dork == "" {
+		dork = target.Query
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in search routine: %v", r)
 			results <- SearchResult{
-				Domain: domain,
-				Error:  fmt.Sprintf("Search routine panic: %v", r),
+				Domain:    domain,
+				Error:     fmt.Sprintf("Search routine panic: %v", r),
+				ErrorKind: ErrorPanic,
 			}
 		}
 	}()
 
 	localSet := NewSubdomainSet()
 	startIndex := int64(1)
-	totalResults := int64(100)
-	resultsPerPage := int64(10)
+	totalResults, resultsPerPage := searchLimits()
+	fetched := int64(0)
+	partial := false
+	emptyPages := 0
+
+	// abort reports a failed page. Without earlier results the domain fails;
+	// otherwise the earlier pages are kept and the domain is marked partial.
+	abort := func(kind ErrorKind, msg string) bool {
+		if fetched == 0 {
+			results <- SearchResult{Domain: domain, Error: msg, ErrorKind: kind}
+			return true
+		}
+		logger.Warn("Keeping %d results for domain %s as partial (%s)", fetched, domain, msg)
+		partial = true
+		return false
+	}
 
-	for startIndex < totalResults {
+pages:
+	for startIndex <= totalResults {
 		select {
 		case <-ctx.Done():
-			results <- SearchResult{
-				Domain: domain,
-				Error:  "Search timeout",
+			if abort(ErrorTimeout, "Search timeout") {
+				return
 			}
-			return
+			break pages
 		default:
 			logger.Trace("Searching page starting at index: %d for domain: %s", startIndex, domain)
-			req := svc.Cse.List().Cx(cseID).Q(query).Num(resultsPerPage).Start(startIndex)
-			resp, err := req.Do()
-			if err != nil {
-				logger.Error("Search failed for domain %s: %v", domain, err)
-				results <- SearchResult{
-					Domain: domain,
-					Error:  fmt.Sprintf("Search failed: %v", err),
+			if err := waitWhilePaused(ctx); err != nil {
+				if abort(ErrorTimeout, "Search timeout") {
+					return
 				}
-				return
+				break pages
+			}
+			num := pageSize(startIndex, totalResults, resultsPerPage)
+			resp, cached := searchCache.get(cacheQuery(target, query), startIndex, num)
+			if cached {
+				logger.Trace("Serving page at index %d for domain %s from cache", startIndex, domain)
+			} else {
+				key, err := pool.AcquireEngine(ctx, priority, target.CSEID)
+				if errors.Is(err, errCostCapReached) {
+					// The pool warns once when the cap is reached.
+					if abort(ErrorQuota, fmt.Sprintf("Search stopped: %v", err)) {
+						return
+					}
+					break pages
+				}
+				if errors.Is(err, errKeysExhausted) {
+					logger.Error("Search failed for domain %s: %v", domain, err)
+					if abort(ErrorQuota, fmt.Sprintf("Search failed: %v", err)) {
+						return
+					}
+					break pages
+				}
+				if err != nil {
+					if abort(ErrorTimeout, "Search timeout") {
+						return
+					}
+					break pages
+				}
+				req := newListCall(key.svc, key.cseID, query, startIndex, num)
+				if target.DateRange != "" {
+					req.Sort("date:r:" + target.DateRange)
+				}
+				resp, err = searchWithRetry(ctx, req, domain)
+				pool.Release(key, err)
+				if err != nil && classifyError(err) == ErrorQuota {
+					// Release took the key out of the pool; the page is
+					// retried on the next key that still has quota.
+					logger.Debug("Requeueing page %d of domain %s after key %s ran out of quota", startIndex, domain, key.name)
+					continue pages
+				}
+				if err != nil {
+					logger.Error("Search failed for domain %s: %v", domain, err)
+					if abort(classifyError(err), fmt.Sprintf("Search failed: %v", err)) {
+						return
+					}
+					break pages
+				}
+				pool.Served(key, domain)
+				searchCache.put(cacheQuery(target, query), startIndex, num, resp)
 			}
 
+			eventLog.emit(Event{Event: "page_fetched", Domain: domain, Query: query, Start: startIndex, Items: len(resp.Items), Cached: cached})
 			if resp.Items == nil {
-				break
+				break pages
 			}
 
+			fetched += int64(len(resp.Items))
+			kept := 0
 			for _, item := range resp.Items {
-				if *subdomains {
-					if subs := extractSubdomains(domain, item.Link); len(subs) > 0 {
-						for _, sub := range subs {
-							localSet.Add(sub)
-						}
+				collectedAt := time.Now().UTC()
+				link := resultLink(item.Link)
+				if urlDenylist.denies(link) {
+					logger.Debug("Suppressed %s on -url-denylist", link)
+					continue
+				}
+				kept++
+				// -auto-bucket files each result under its own domain.
+				domain := domain
+				if *autoBucket {
+					domain = registrableDomain(hostOf(item.Link))
+				}
+				if *urlsOnly {
+					result := Result{URL: link, Domain: domain, CollectedAt: collectedAt, Dork: dork}
+					if *splitFiletype != "" {
+						result.FileType = fileTypeOf(item.Mime, item.FileFormat, item.Link)
+					}
+					if recordResult(result) {
+						eventLog.emit(Event{Event: "result_found", Domain: domain, Query: query, URL: link})
+					}
+					continue
+				}
+
+				subs := extractSubdomains(domain, item.Link)
+				result := Result{
+					Title:       item.Title,
+					URL:         link,
+					Snippet:     item.Snippet,
+					Domain:      domain,
+					Host:        hostOf(item.Link),
+					Subdomains:  subs,
+					CollectedAt: collectedAt,
+					Dork:        dork,
+				}
+				if *groupBy == "filetype" || *splitFiletype != "" {
+					result.FileType = fileTypeOf(item.Mime, item.FileFormat, item.Link)
+				}
+				if *imagesMode {
+					result.Image = imageMetaOf(item)
+					if result.Image.Geotagged() {
+						logger.Warn("Geotagged image %s: %s,%s", item.Link, result.Image.Latitude, result.Image.Longitude)
 					}
 				}
-				logger.Info("%sFound:%s %s", colorGreen, colorReset, item.Link)
+				if !recordResult(result) {
+					continue
+				}
+				eventLog.emit(Event{Event: "result_found", Domain: domain, Query: query, URL: link, Title: item.Title})
+				for _, sub := range subs {
+					localSet.Add(sub)
+				}
+				if !*quietProgress {
+					logger.Info("%sFound:%s %s", colorGreen, colorReset, link)
+				}
 			}
 
 			startIndex += resultsPerPage
-			if len(resp.Items) < int(resultsPerPage) {
-				break
+			if *headLimit > 0 || len(resp.Items) < int(num) {
+				break pages
+			}
+			if kept == 0 {
+				emptyPages++
+			} else {
+				emptyPages = 0
+			}
+			if *maxEmptyPages > 0 && emptyPages >= *maxEmptyPages {
+				logger.Debug("Domain %s stopped after %d pages with nothing kept (-max-empty-pages)", domain, emptyPages)
+				break pages
 			}
 
-			time.Sleep(time.Second) // Rate limiting
+			if !cached {
+				time.Sleep(pageDelay) // Rate limiting
+			}
 		}
 	}
 
+	truncated := fetched >= totalResults
+	if truncated && totalResults < apiResultCeiling {
+		logger.Debug("Domain %s stopped at the -pages or -total-per-domain limit of %d results", domain, totalResults)
+	} else if truncated {
+		logger.Warn("Domain %s hit the %d-result API ceiling, results are truncated; narrow the query to see more", domain, totalResults)
+	}
+
 	results <- SearchResult{
 		Domain:     domain,
 		Subdomains: localSet.ToSlice(),
+		Queries:    []string{query},
+		Count:      int(fetched),
+		Truncated:  truncated,
+		Partial:    partial,
 	}
 }
 
-func processDomains(domains []string, svc *customsearch.Service, cseID string) map[string][]string {
-	resultsChan := make(chan SearchResult, len(domains))
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
+func hostOf(link string) string {
+	parsedURL, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// recordResult hands a result to the result writer and reports whether it
+// was kept. Once -head results have been stored, later ones are dropped.
+// With -low-memory the result is written out instead of being stored.
+func recordResult(result Result) bool {
+	if !*lowMemory && *headLimit > 0 && storedResults.Add(1) > int64(*headLimit) {
+		return false
+	}
+	resultQueue <- writeOp{result: result}
+	return true
+}
+
+func resetResults() {
+	results = nil
+	storedResults.Store(0)
+}
+
+// collectedResults returns the stored results sorted by domain. It must not
+// be called while the result writer is running.
+func collectedResults() []Result {
+	collected := make([]Result, len(results))
+	copy(collected, results)
+	sort.SliceStable(collected, func(i, j int) bool { return collected[i].Domain < collected[j].Domain })
+	return collected
+}
+
+// apiResultCeiling is the most results the Custom Search API serves for one
+// query, however many pages are requested.
+const apiResultCeiling = 100
+
+// pageDelay spaces out the API requests for the pages of one query.
+var pageDelay = time.Second
+
+// pageSize is how many results to request from start: a full page of 10,
+// cut short on the last page by -total-per-domain and by the API, which
+// rejects start+num beyond 101 with a 400 "invalid value".
+func pageSize(start, total, perPage int64) int64 {
+	return max(0, min(perPage, total-start+1, apiResultCeiling+1-start))
+}
+
+// searchLimits returns how many results to fetch for a query and how many
+// to request per page: -pages pages of -per-page results, capped by
+// -total-per-domain and the API ceiling.
+func searchLimits() (total, perPage int64) {
+	perPage = int64(*perPageArg)
+	return min(int64(*pagesArg)*perPage, int64(*totalPerDomain), apiResultCeiling), perPage
+}
 
-	var wg sync.WaitGroup
-	sem := make(chan bool, *concurrent)
+var errHeadReached = errors.New("-head limit reached")
 
-	for _, domain := range domains {
-		logger.Info("Starting search for domain: %s", domain)
-		wg.Add(1)
-		go func(d string) {
-			defer wg.Done()
-			sem <- true
-			performSearch(ctx, svc, cseID, constructQuery(d, *queryArg), d, resultsChan)
-			<-sem
-		}(domain)
+func processDomains(parent context.Context, targets []Target, pool *KeyPool) map[string]SearchResult {
+	resultsChan := make(chan SearchResult, len(targets))
+	timeoutCtx, deadline, cancel := withPausableTimeout(parent, *timeout)
+	defer cancel()
+	searchTimeout = deadline
+	ctx, stop := context.WithCancelCause(timeoutCtx)
+	defer stop(nil)
+
+	progress.start(targets)
+
+	// pending counts the searches left per domain, so it is only finished
+	// once every dork for it has completed.
+	pending := make(map[string]int)
+	errs := make(map[string]int)
+	for _, target := range targets {
+		pending[target.Domain]++
 	}
 
-	wg.Wait()
-	close(resultsChan)
+	// Deferred after closeAll so the writer drains before files are closed.
+	defer jsonlStreams.closeAll()
+	defer startResultWriter()()
 
-	results := make(map[string][]string)
+	// resultsChan holds every result, so the workers never block on it.
+	go func() {
+		forEachBounded(targets, *concurrent, func(target Target) {
+			if !*quietProgress {
+				logger.Info("Starting search for domain: %s", target.Domain)
+			}
+			eventLog.emit(Event{Event: "domain_start", Domain: target.Domain, Query: constructQuery(target.Domain, target.Query)})
+			performSearch(ctx, pool, target, resultsChan)
+		})
+		close(resultsChan)
+	}()
+
+	results := make(map[string]SearchResult)
+	found := 0
 	for result := range resultsChan {
+		pending[result.Domain]--
+		if result.Error != "" && errors.Is(context.Cause(ctx), errHeadReached) {
+			// Searches cut short by -head are not failures.
+			progress.done(SearchResult{Domain: result.Domain}, 0)
+			finishDomain(results, result.Domain, pending, errs)
+			continue
+		}
+		progress.done(result, result.Count)
+		found += result.Count
+		if *headLimit > 0 && found >= *headLimit {
+			stop(errHeadReached)
+		}
 		if result.Error != "" {
-			logger.Error("Error for domain %s: %s", result.Domain, result.Error)
+			logger.Error("Error for domain %s (%s): %s", result.Domain, result.ErrorKind, result.Error)
+			errs[result.Domain]++
+		} else if prev, ok := results[result.Domain]; ok {
+			// Several dorks for one domain add up to a single entry.
+			prev.Queries = unionSorted(prev.Queries, result.Queries)
+			prev.Subdomains = unionSorted(prev.Subdomains, result.Subdomains)
+			prev.Count += result.Count
+			prev.Truncated = prev.Truncated || result.Truncated
+			prev.Partial = prev.Partial || result.Partial
+			results[result.Domain] = prev
 		} else {
-			results[result.Domain] = result.Subdomains
+			results[result.Domain] = result
 		}
+		finishDomain(results, result.Domain, pending, errs)
 	}
 	return results
 }
 
-func getAllDomains() []string {
-	domains := []string{*domainArg}
-	domains = append(domains, flag.Args()...) // Add any additional domains from command line args
-	return domains
+// finishDomain closes a domain's -output-jsonl-per-domain file and writes
+// its -domain-summary-fd line once none of its searches are pending.
+func finishDomain(results map[string]SearchResult, domain string, pending, errs map[string]int) {
+	if pending[domain] > 0 {
+		return
+	}
+	resultQueue <- writeOp{result: Result{Domain: domain}, finish: true}
+	eventLog.emit(Event{Event: "domain_complete", Domain: domain, Results: results[domain].Count, Failed: errs[domain]})
+	if domainLines == nil {
+		return
+	}
+	result, ok := results[domain]
+	if !ok {
+		result = SearchResult{Domain: domain}
+	}
+	fmt.Fprintln(domainLines, formatDomainLine(result, errs[domain]))
 }
 
-func outputSubdomains(results map[string][]string) {
-	switch *formatArg {
-	case "json":
-		if err := outputJSON(results); err != nil && !*silent {
-			logger.Error("Failed to output JSON: %v", err)
+// flagDomains marks every domain with more than threshold results and returns
+// them, most results first.
+func flagDomains(results map[string]SearchResult, threshold int) []SearchResult {
+	var flagged []SearchResult
+	for domain, result := range results {
+		if result.Count > threshold {
+			result.Flagged = true
+			results[domain] = result
+			flagged = append(flagged, result)
 		}
-	case "txt":
-		outputTXT(results)
-	case "csv":
-		if err := outputCSV(results); err != nil && !*silent {
-			logger.Error("Failed to output CSV: %v", err)
+	}
+	sort.Slice(flagged, func(i, j int) bool {
+		if flagged[i].Count != flagged[j].Count {
+			return flagged[i].Count > flagged[j].Count
+		}
+		return flagged[i].Domain < flagged[j].Domain
+	})
+	return flagged
+}
+
+func sortedSearchResults(results map[string]SearchResult) []SearchResult {
+	sorted := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Domain < sorted[j].Domain })
+	return sorted
+}
+
+func getAllDomains() []Target {
+	var targets []Target
+	var inputs []string
+	if *domainArg != "" {
+		inputs = append(inputs, *domainArg)
+	}
+	inputs = append(inputs, flag.Args()...) // Add any additional domains from command line args
+	for _, input := range inputs {
+		domain := cleanDomain(input)
+		if domain == "" {
+			logger.Error("Invalid domain %q", input)
+			os.Exit(1)
+		}
+		targets = append(targets, Target{Domain: domain, Query: *queryArg})
+	}
+
+	if *domainList != "" {
+		listed, err := loadDomainList(*domainList)
+		if err != nil {
+			logger.Error("Failed to load domain list: %v", err)
+			os.Exit(1)
+		}
+		targets = append(targets, listed...)
+	}
+	targets = dedupeTargets(targets)
+	if *limitDomains > 0 {
+		targets = limitTargets(targets, *limitDomains)
+	}
+	return targets
+}
+
+// dedupeTargets drops repeats of a target, such as a domain given with -d
+// that is also in the -dL file, so it is only searched once. Domains are
+// already normalized, so "https://Example.com/" repeats "example.com". The
+// same domain with a different "domain | query" query is kept.
+func dedupeTargets(targets []Target) []Target {
+	seen := make(map[Target]bool, len(targets))
+	kept := targets[:0]
+	for _, target := range targets {
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		kept = append(kept, target)
+	}
+	if skipped := len(targets) - len(kept); skipped > 0 {
+		logger.Info("Skipping %d duplicate domain inputs", skipped)
+	}
+	return kept
+}
+
+// limitTargets keeps the targets of the first n distinct domains, for trying
+// a config against the head of a large list with -limit-domains.
+func limitTargets(targets []Target, n int) []Target {
+	seen := make(map[string]bool)
+	var kept []Target
+	for _, target := range targets {
+		if !seen[target.Domain] {
+			if len(seen) == n {
+				continue
+			}
+			seen[target.Domain] = true
+		}
+		kept = append(kept, target)
+	}
+	if len(kept) < len(targets) {
+		logger.Info("Searching only the first %d domains (-limit-domains)", n)
+	}
+	return kept
+}
+
+func loadDomainList(filename string) ([]Target, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var targets []Target
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target, err := parseTargetLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineNo, err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, scanner.Err()
+}
+
+// parseTargetLine parses a domain list entry of the form "domain" or
+// "domain | query". Only the first separator is significant so queries may
+// still use "|" as Google's OR operator.
+func parseTargetLine(line string) (Target, error) {
+	domain, query, hasQuery := strings.Cut(line, "|")
+	domain = strings.TrimSpace(domain)
+	if domain == "" {
+		return Target{}, fmt.Errorf("missing domain before '|'")
+	}
+	if strings.ContainsAny(domain, " \t") {
+		redactTarget(domain)
+		return Target{}, fmt.Errorf("invalid domain %q (use 'domain | query' to add a query)", domain)
+	}
+	raw := domain
+	if domain = cleanDomain(raw); domain == "" {
+		return Target{}, fmt.Errorf("invalid domain %q", raw)
+	}
+
+	query = strings.TrimSpace(query)
+	if !hasQuery || query == "" {
+		if hasQuery {
+			logger.Debug("Empty query override for %s, using global query", domain)
+		}
+		return Target{Domain: domain, Query: *queryArg}, nil
+	}
+	return Target{Domain: domain, Query: query}, nil
+}
+
+func outputSubdomains(results map[string]SearchResult, out outputTarget) error {
+	if *appendOutput {
+		merged, err := mergeExistingSubdomains(out.path, out.format, results)
+		if err != nil {
+			return err
 		}
+		results = merged
+	}
+	if *onlyWithSubs {
+		results = withSubdomains(results)
+	}
+	if *flatSubs {
+		return outputFlatSubdomains(results, out)
+	}
+
+	switch out.format {
+	case "json":
+		return outputJSON(results, out)
+	case "csv":
+		return outputCSV(results, out)
+	case "template":
+		return outputTemplated(TemplateData{Domains: sortedSearchResults(results)}, out)
 	default:
-		outputTXT(results)
+		return outputTXT(results, out)
+	}
+}
+
+func withSubdomains(results map[string]SearchResult) map[string]SearchResult {
+	kept := make(map[string]SearchResult, len(results))
+	for domain, result := range results {
+		if len(result.Subdomains) > 0 {
+			kept[domain] = result
+		}
 	}
+	return kept
 }
 
-func outputJSON(results map[string][]string) error {
-	output, err := json.MarshalIndent(results, "", "  ")
+func outputJSON(results map[string]SearchResult, out outputTarget) error {
+	var doc interface{} = sortedSearchResults(results)
+	if !*detailedJSON {
+		subs := make(map[string][]string, len(results))
+		for domain, result := range results {
+			subs[domain] = result.Subdomains
+		}
+		doc = subs
+	}
+
+	output, err := marshalOutput(doc)
 	if err != nil {
 		return err
 	}
 
-	if *outputArg != "" {
-		return ioutil.WriteFile(*outputArg, output, 0644)
+	if out.path != "" {
+		return writeOutputFile(out.path, output)
 	}
 	fmt.Println(string(output))
 	return nil
 }
 
-func outputTXT(results map[string][]string) {
+func outputTXT(results map[string]SearchResult, out outputTarget) error {
 	var output strings.Builder
-	for domain, subdomains := range results {
+	for _, result := range sortedSearchResults(results) {
 		if len(results) > 1 {
-			output.WriteString(fmt.Sprintf("%s:\n", domain))
+			output.WriteString(fmt.Sprintf("%s:\n", result.Domain))
 		}
-		for _, subdomain := range subdomains {
+		meta := make(map[string]HostMeta, len(result.Meta))
+		for _, m := range result.Meta {
+			meta[m.Host] = m
+		}
+		for _, subdomain := range result.Subdomains {
 			output.WriteString(subdomain + "\n")
+			output.WriteString(formatHostMeta(meta[subdomain]))
 		}
 		if len(results) > 1 {
 			output.WriteString("\n")
 		}
 	}
 
-	if *outputArg != "" {
-		ioutil.WriteFile(*outputArg, []byte(output.String()), 0644)
-		return
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
+
+// flatSubdomains merges every domain's subdomains into one sorted list
+// without duplicates, for -flat-subs.
+func flatSubdomains(results map[string]SearchResult) []string {
+	var flat []string
+	for _, result := range results {
+		flat = unionSorted(flat, result.Subdomains)
+	}
+	return flat
+}
+
+func outputFlatSubdomains(results map[string]SearchResult, out outputTarget) error {
+	var output strings.Builder
+	for _, subdomain := range flatSubdomains(results) {
+		output.WriteString(subdomain + "\n")
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
 	}
 	fmt.Print(output.String())
+	return nil
 }
 
-func outputCSV(results map[string][]string) error {
+func outputCSV(results map[string]SearchResult, out outputTarget) error {
 	var output strings.Builder
 	writer := csv.NewWriter(&output)
 
 	writer.Write([]string{"Domain", "Subdomain"})
 
-	for domain, subdomains := range results {
-		for _, subdomain := range subdomains {
-			writer.Write([]string{domain, subdomain})
+	for _, result := range sortedSearchResults(results) {
+		for _, subdomain := range result.Subdomains {
+			writer.Write([]string{result.Domain, subdomain})
 		}
 	}
 	writer.Flush()
 
-	if *outputArg != "" {
-		return ioutil.WriteFile(*outputArg, []byte(output.String()), 0644)
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
 	}
 	fmt.Print(output.String())
 	return nil
 }
 
-func main() {
-	startTime := time.Now()
-	flag.Parse()
-
-	if *showVersion && !*silent {
-		fmt.Printf(BANNER, VERSION)
-		return
+func outputResults(results []Result, searches map[string]SearchResult, out outputTarget) error {
+	if *portsOnly {
+		return outputPorts(results, out)
 	}
-
-	setupLogger()
-	if !*silent {
-		logger.Info("Starting Google Dorker v%s", VERSION)
+	if out.format == "urllist" {
+		return outputURLList(results, out)
 	}
-
-	if *domainArg == "" {
-		if !*silent {
-			flag.Usage()
-		}
-		os.Exit(1)
+	if out.format == "template" {
+		return outputTemplated(TemplateData{Domains: sortedSearchResults(searches), Results: results}, out)
+	}
+	if *snippetOnly {
+		return outputSnippets(results, out)
+	}
+	if *urlsOnly {
+		return outputURLs(results, out)
+	}
+	if *groupBy == "filetype" {
+		return outputFileTypeGroups(results, out)
 	}
 
-	configFile := loadConfig()
-	config := loadAPIConfig(configFile)
-	logger.Debug("Configuration loaded successfully")
+	switch out.format {
+	case "json":
+		return outputResultsJSON(results, searches, out)
+	case "csv":
+		return outputResultsCSV(results, out)
+	default:
+		return outputResultsTXT(results, out)
+	}
+}
 
-	rand.Seed(time.Now().UnixNano())
-	googleAPI := config.GoogleAPI[rand.Intn(len(config.GoogleAPI))]
-	googleCSEID := config.GoogleCSEID[rand.Intn(len(config.GoogleCSEID))]
+func outputURLs(results []Result, out outputTarget) error {
+	var output []byte
+	switch out.format {
+	case "json":
+		urls := make(map[string][]string)
+		for _, result := range results {
+			urls[result.Domain] = append(urls[result.Domain], result.URL)
+		}
+		data, err := marshalOutput(urls)
+		if err != nil {
+			return err
+		}
+		output = append(data, '\n')
+	case "csv":
+		var buf strings.Builder
+		writer := csv.NewWriter(&buf)
+		writer.Write([]string{"Domain", "URL"})
+		for _, result := range results {
+			writer.Write([]string{result.Domain, result.URL})
+		}
+		writer.Flush()
+		output = []byte(buf.String())
+	default:
+		var buf strings.Builder
+		for _, result := range results {
+			buf.WriteString(result.URL + "\n")
+		}
+		output = []byte(buf.String())
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, output)
+	}
+	fmt.Print(string(output))
+	return nil
+}
+
+func outputResultsJSON(results []Result, searches map[string]SearchResult, out outputTarget) error {
+	if !*detailedJSON {
+		output, err := marshalOutput(results)
+		if err != nil {
+			return err
+		}
+		if out.path != "" {
+			return writeOutputFile(out.path, output)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	grouped := make(map[string]SearchResult, len(searches))
+	for domain, search := range searches {
+		grouped[domain] = SearchResult{
+			Domain:    domain,
+			Queries:   search.Queries,
+			Truncated: search.Truncated,
+			Partial:   search.Partial,
+			Flagged:   search.Flagged,
+		}
+	}
+	for _, result := range results {
+		group := grouped[result.Domain]
+		group.Domain = result.Domain
+		group.Results = append(group.Results, result)
+		grouped[result.Domain] = group
+	}
+
+	output, err := marshalOutput(sortedSearchResults(grouped))
+	if err != nil {
+		return err
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, output)
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+func outputResultsTXT(results []Result, out outputTarget) error {
+	var output strings.Builder
+	for _, result := range results {
+		output.WriteString(formatResultTXT(result))
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
+
+func outputResultsCSV(results []Result, out outputTarget) error {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	writer.Write(resultsCSVHeader)
+	for _, result := range results {
+		writer.Write(resultCSVRecord(result))
+	}
+	writer.Flush()
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
+
+var resultsCSVHeader = []string{"Domain", "Host", "Subdomains", "Title", "URL", "Snippet", "CollectedAt"}
+
+func resultCSVRecord(result Result) []string {
+	return []string{result.Domain, result.Host, strings.Join(result.Subdomains, " "), result.Title, result.URL, result.Snippet, formatCollectedAt(result.CollectedAt)}
+}
+
+func formatResultTXT(result Result) string {
+	text := fmt.Sprintf("%s\n%s\n", result.Title, result.URL)
+	if result.Snippet != "" {
+		text += result.Snippet + "\n"
+	}
+	return text + "\n"
+}
+
+func formatCollectedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func filterSeenSubdomains(results map[string]SearchResult, seen *BloomFilter) map[string]SearchResult {
+	filtered := make(map[string]SearchResult, len(results))
+	for domain, result := range results {
+		unseen := []string{}
+		for _, sub := range result.Subdomains {
+			if !seen.Test("sub:" + sub) {
+				unseen = append(unseen, sub)
+			}
+		}
+		result.Subdomains = unseen
+		filtered[domain] = result
+	}
+	return filtered
+}
+
+func filterSeenResults(results []Result, seen *BloomFilter) []Result {
+	var unseen []Result
+	for _, result := range results {
+		if !seen.Test("url:" + result.URL) {
+			unseen = append(unseen, result)
+		}
+	}
+	return unseen
+}
+
+func rememberSubdomains(seen *BloomFilter, searches map[string]SearchResult) {
+	for _, search := range searches {
+		for _, sub := range search.Subdomains {
+			seen.Add("sub:" + sub)
+		}
+	}
+}
+
+func rememberResults(seen *BloomFilter, results []Result) {
+	for _, result := range results {
+		seen.Add("url:" + result.URL)
+	}
+}
+
+// outputTarget is one output to write: a single format, to the file at path
+// or to stdout when path is empty.
+type outputTarget struct {
+	format string
+	path   string
+}
+
+// outputFormats splits -format into the formats to write.
+func outputFormats() []string {
+	var formats []string
+	for _, format := range strings.Split(*formatArg, ",") {
+		if format = strings.TrimSpace(format); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) == 0 {
+		formats = []string{"txt"}
+	}
+	return formats
+}
+
+// outputPath returns the file a format is written to. With several formats
+// each one replaces the extension of -o, so "-o scan.txt -format json,csv"
+// writes scan.json and scan.csv.
+func outputPath(base, format string, multi bool) string {
+	if !multi || base == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "." + format
+}
+
+// writeAllFormats calls write once per requested format, with the file that
+// format goes to. A failing format does not stop the others from being
+// written; every failure is logged and the returned error reports how many
+// formats failed.
+func writeAllFormats(write func(out outputTarget) error) error {
+	formats := outputFormats()
+	if len(formats) == 1 {
+		return write(outputTarget{format: formats[0], path: *outputArg})
+	}
+
+	failed := 0
+	for _, format := range formats {
+		if err := write(outputTarget{format: format, path: outputPath(*outputArg, format, true)}); err != nil {
+			logger.Error("Failed to write %s output: %v", format, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d output formats failed", failed, len(formats))
+	}
+	return nil
+}
+
+func writeOutputFile(path string, data []byte) error {
+	if *appendOutput && !*subdomains {
+		return appendCSVFile(path, data)
+	}
+	if outputRecipients != nil {
+		encrypted, err := encryptOutput(data, outputRecipients)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %v", path, err)
+		}
+		data = encrypted
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if *noOverwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	file, err := openOutput(path, flags)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("refusing to overwrite existing file %s", path)
+		}
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runOptions carries the state a search run needs beyond the flags.
+type runOptions struct {
+	seen            *BloomFilter
+	baseline        map[string]time.Time
+	match           *regexp.Regexp
+	wordlistCharset *regexp.Regexp
+	webhookTemplate *template.Template
+}
+
+// runSearch searches every target once and writes, reports and remembers
+// what it found. It returns the output error, if any.
+func runSearch(ctx context.Context, pool *KeyPool, targets []Target, run runOptions, startTime time.Time) error {
+	eventLog.emit(Event{Event: "run_start", Time: startTime.UTC(), Targets: len(targets)})
+	resetResults()
+	if *lowMemory && !*subdomains {
+		sink, err := newResultSink(outputFormats()[0])
+		if err != nil {
+			logger.Error("Failed to write output: %v", err)
+			return err
+		}
+		lowMemorySink = sink
+	}
+	results := processDomains(ctx, targets, pool)
+	if *priorityFile != "" {
+		for _, tier := range priorityTiers(targets, results) {
+			logger.Info("Priority %d: %d/%d domains searched", tier.Priority, tier.Searched, tier.Domains)
+		}
+	}
+	found := collectedResults()
+	if *autoBucket {
+		results = bucketResults(results, found)
+		logger.Info("Bucketed the results into %d domains", len(results))
+	}
+	if (*allCSE || *deepPaginate) && !*lowMemory {
+		found = dedupeDomainURLs(found, results)
+	}
+	var flagged []SearchResult
+	if *highlightMin > 0 {
+		flagged = flagDomains(results, *highlightMin)
+	}
+
+	if *dedupeSnippet {
+		var dropped int
+		if found, dropped = dedupeSnippets(found); dropped > 0 {
+			logger.Info("Collapsed %d near-duplicate results", dropped)
+		}
+	}
+	if run.match != nil {
+		found = matchSnippets(found, run.match)
+		logger.Info("%d results have a snippet matching -match", len(found))
+	}
+	if run.baseline != nil {
+		stampFirstSeen(found, run.baseline, startTime)
+	}
+
+	// Findings seen by an earlier run are dropped before any destination
+	// is written, so the -watch webhook gate below counts only new ones.
+	if *subdomains {
+		if run.seen != nil {
+			results = filterSeenSubdomains(results, run.seen)
+		}
+		if *minDepth > 0 {
+			results = filterSubdomainDepth(results, *minDepth)
+		}
+	} else if run.seen != nil && lowMemorySink == nil {
+		found = filterSeenResults(found, run.seen)
+	}
+
+	output := func() error {
+		if *subdomains {
+			if *probeMeta {
+				probeCtx, cancel := context.WithTimeout(ctx, *timeout)
+				attachHostMeta(probeCtx, &http.Client{Timeout: *probeTimeout}, results, *probeWorkers)
+				cancel()
+			}
+			return writeAllFormats(func(out outputTarget) error { return outputSubdomains(results, out) })
+		}
+		if lowMemorySink != nil {
+			err := lowMemorySink.close()
+			lowMemorySink = nil
+			return err
+		}
+		return writeAllFormats(func(out outputTarget) error { return outputResults(found, results, out) })
+	}
+	var outputErr error
+	destinations := []destination{{name: "output", write: func() error {
+		outputErr = output()
+		return outputErr
+	}}}
+	if *perDorkDir != "" {
+		destinations = append(destinations, destination{name: "-output-per-dork", write: func() error {
+			return writePerDork(*perDorkDir, found, results)
+		}})
+	}
+	if *explodeDir != "" {
+		destinations = append(destinations, destination{name: "-explode", write: func() error {
+			return explodeResults(*explodeDir, found)
+		}})
+	}
+	if *splitFiletype != "" {
+		destinations = append(destinations, destination{name: "-split-by-filetype", write: func() error {
+			return splitByFileType(*splitFiletype, found)
+		}})
+	}
+	if run.wordlistCharset != nil {
+		destinations = append(destinations, destination{name: "-wordlist-out", write: func() error {
+			return writeWordlist(*wordlistOut, extractWords(collectedResults(), *wordlistMinLen, run.wordlistCharset))
+		}})
+	}
+	if *elasticURL != "" {
+		destinations = append(destinations, destination{name: "-elasticsearch", remote: true, write: func() error {
+			var docs []any
+			if *subdomains {
+				for _, result := range sortedSearchResults(results) {
+					docs = append(docs, result)
+				}
+			} else {
+				for _, result := range found {
+					docs = append(docs, result)
+				}
+			}
+			return postElasticsearch(&http.Client{Timeout: 30 * time.Second}, *elasticURL, docs)
+		}})
+	}
+	// In -watch mode the webhook only fires for runs with new findings.
+	if *webhookURL != "" && (*watchInterval == 0 || newFindings(results, found) > 0) {
+		destinations = append(destinations, destination{name: "-webhook", remote: true, write: func() error {
+			body, err := renderWebhookBody(run.webhookTemplate, WebhookData{
+				Run:     buildSummary(startTime, results, outputErr),
+				Domains: sortedSearchResults(results),
+				Results: found,
+			})
+			if err != nil {
+				return err
+			}
+			return postWebhook(&http.Client{Timeout: 30 * time.Second}, *webhookURL, body)
+		}})
+	}
+	statuses := deliver(destinations)
+
+	// Only items that were actually written count as seen.
+	if run.seen != nil && outputErr == nil {
+		if *subdomains {
+			rememberSubdomains(run.seen, results)
+		} else {
+			rememberResults(run.seen, found)
+		}
+		if *bloomFile != "" {
+			if err := run.seen.Save(*bloomFile); err != nil {
+				logger.Error("Failed to save bloom filter: %v", err)
+			}
+		}
+	}
+
+	completed, failed, resultCount := progress.counts()
+	logger.Info("Searched %d domains: %d results, %d failed", completed, resultCount, failed)
+	if urlDenylist != nil {
+		logger.Info("Suppressed %d results on -url-denylist", urlDenylist.suppressed.Load())
+	}
+	if *verboseTiming {
+		logger.Info("Average API page fetch: %v", pageLatency.average().Round(time.Millisecond))
+	}
+	usage := pool.Usage()
+	spend := pool.EstimatedSpend()
+	if spend > 0 || *maxCost > 0 {
+		logger.Info("Estimated spend beyond the free queries: $%.2f", spend)
+	}
+	for _, key := range usage {
+		status := fmt.Sprintf(", about %d of %d free queries left", key.EstimatedFreeLeft, freeDailyQueries)
+		if key.Exhausted {
+			status = ", out of quota"
+		}
+		logger.Info("Key %s (CSE %s): %d queries for %d domains%s", key.Key, key.CSEID, key.Queries, key.Domains, status)
+	}
+	for _, result := range flagged {
+		logger.Warn("High-value domain %s: %d results (threshold %d)", result.Domain, result.Count, *highlightMin)
+	}
+	if *failedReport && !*silent {
+		writeFailedReport(os.Stderr, progress.failedSearches())
+	}
+
+	runErr := destinationsErr(statuses)
+	complete := Event{Event: "run_complete", Targets: len(targets), Results: resultCount, Failed: failed, DurationSeconds: time.Since(startTime).Seconds()}
+	if runErr != nil {
+		complete.Error = runErr.Error()
+	}
+	eventLog.emit(complete)
+
+	if *summaryFD > 0 {
+		summary := buildSummary(startTime, results, outputErr)
+		summary.Destinations = statuses
+		summary.Keys = usage
+		summary.EstimatedSpendUSD = spend
+		if err := writeSummary(*summaryFD, summary); err != nil {
+			logger.Error("Failed to write summary to fd %d: %v", *summaryFD, err)
+		}
+	}
+
+	return runErr
+}
+
+// newFindings counts what a run wrote: subdomains with -subs, else results.
+func newFindings(results map[string]SearchResult, found []Result) int {
+	if !*subdomains {
+		return len(found)
+	}
+	count := 0
+	for _, result := range results {
+		count += len(result.Subdomains)
+	}
+	return count
+}
+
+func main() {
+	startTime := time.Now()
+	flag.Parse()
+
+	if *showVersion && !*silent {
+		fmt.Printf(BANNER, VERSION)
+		return
+	}
+
+	setupLogger()
+	if !*silent {
+		logger.Info("Starting Google Dorker v%s", VERSION)
+	}
+	defer exitIfStrict()
+
+	if credentialsFromFlags() && !hasCredentials(flagConfig()) {
+		logger.Error("-api-key and -cse-id must be given together; credentials from flags replace the config file and environment entirely")
+		os.Exit(1)
+	}
+	if path, _, err := findConfigFile(); err == nil && path != "" && !credentialsFromFlags() {
+		applyOutputConfig(readOutputConfig(path), flag.CommandLine)
+	}
+
+	if *domainArg == "" && *domainList == "" && !*autoBucket && !*listEnginesArg && !*printConfig {
+		if !*silent {
+			flag.Usage()
+		}
+		os.Exit(1)
+	}
+
+	if *urlsOnly && *subdomains {
+		logger.Error("-urls and -subs cannot be used together")
+		os.Exit(1)
+	}
+
+	if *autoBucket && (*domainArg != "" || *domainList != "" || flag.NArg() > 0 || *dorkFile != "") {
+		logger.Error("-auto-bucket runs the -q query once without site: and cannot be used with -d, -dL, domain arguments or -dorks")
+		os.Exit(1)
+	}
+
+	if *autoBucket && *queryArg == "" {
+		logger.Error("-auto-bucket requires -q")
+		os.Exit(1)
+	}
+
+	if *stripSlash && !*normalizeURLs {
+		logger.Error("-strip-trailing-slash requires -normalize-urls")
+		os.Exit(1)
+	}
+
+	if slices.Contains(outputFormats(), "template") != (*templateFile != "") {
+		logger.Error("-format template and -template-file must be used together")
+		os.Exit(1)
+	}
+
+	if *eventsDest == "-" && *outputArg == "" {
+		logger.Error("-events - writes to stdout and requires -o for the results")
+		os.Exit(1)
+	}
+
+	if *encryptTo != "" && (*outputArg == "" || *appendOutput) {
+		logger.Error("-encrypt-to requires -o and cannot be used with -append")
+		os.Exit(1)
+	}
+	if *encryptTo != "" {
+		// These write findings to disk or stdout in plain text beside -o.
+		for name, value := range map[string]string{
+			"-explode-dir":             *explodeDir,
+			"-split-by-filetype":       *splitFiletype,
+			"-wordlist-out":            *wordlistOut,
+			"-output-jsonl-per-domain": *jsonlDir,
+			"-output-per-dork":         *perDorkDir,
+			"-events":                  *eventsDest,
+		} {
+			if value != "" {
+				logger.Error("-encrypt-to only encrypts -o and cannot be used with %s, which would be written in plain text", name)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *appendOutput && *templateFile != "" {
+		logger.Error("-append cannot be used with -format template")
+		os.Exit(1)
+	}
+
+	if *flatSubs && (!*subdomains || *probeMeta || len(outputFormats()) != 1 || outputFormats()[0] != "txt") {
+		logger.Error("-flat-subs requires -subs with txt output and cannot be used with -probe-meta")
+		os.Exit(1)
+	}
+
+	if *subdomains && slices.Contains(outputFormats(), "urllist") {
+		logger.Error("-format urllist lists result URLs and cannot be used with -subs")
+		os.Exit(1)
+	}
+
+	if *splitFiletype != "" && *subdomains {
+		logger.Error("-split-by-filetype needs results and cannot be used with -subs")
+		os.Exit(1)
+	}
+
+	if *portsOnly && (*subdomains || *urlsOnly) {
+		logger.Error("-ports cannot be used with -subs or -urls")
+		os.Exit(1)
+	}
+
+	if *firstSeen && (*subdomains || *urlsOnly) {
+		logger.Error("-first-seen needs full results and cannot be used with -subs or -urls")
+		os.Exit(1)
+	}
+
+	if *baselineFile != "" && !*firstSeen {
+		logger.Error("-baseline requires -first-seen")
+		os.Exit(1)
+	}
+
+	if (*snippetOnly || *matchArg != "") && (*subdomains || *urlsOnly) {
+		logger.Error("-snippet-only and -match need full results and cannot be used with -subs or -urls")
+		os.Exit(1)
+	}
+
+	if *schemaVersion < 1 || *schemaVersion > latestSchemaVersion {
+		logger.Error("Unknown -output-schema-version %d, must be 1 to %d", *schemaVersion, latestSchemaVersion)
+		os.Exit(1)
+	}
+
+	if *maxCost < 0 {
+		logger.Error("Invalid -max-cost %v, must not be negative", *maxCost)
+		os.Exit(1)
+	}
+
+	if *maxEmptyPages < 0 {
+		logger.Error("Invalid -max-empty-pages %d, must not be negative", *maxEmptyPages)
+		os.Exit(1)
+	}
+
+	if *limitDomains < 0 {
+		logger.Error("Invalid -limit-domains %d, must not be negative", *limitDomains)
+		os.Exit(1)
+	}
+
+	if *throttle429 < 0 {
+		logger.Error("Invalid -throttle-on-429 %v, must not be negative", *throttle429)
+		os.Exit(1)
+	}
+
+	if conflicts := lowMemoryConflicts(); *lowMemory && len(conflicts) > 0 {
+		logger.Error("-low-memory cannot be combined with %s", strings.Join(conflicts, ", "))
+		os.Exit(1)
+	}
+
+	if mode, err := parseOutputMode(*outputModeArg); err != nil {
+		logger.Error("Invalid -output-mode: %v", err)
+		os.Exit(1)
+	} else {
+		outputMode = mode
+	}
+
+	if *localeArg != "" {
+		locale, err := parseLocale(*localeArg)
+		if err != nil {
+			logger.Error("Invalid -locale: %v", err)
+			os.Exit(1)
+		}
+		activeLocale = locale
+		// An explicit -geo wins over the locale's region.
+		if *geoArg == "" {
+			*geoArg = locale.gl
+		}
+	}
+
+	if *insecureTLS {
+		logger.Warn("%s-insecure-skip-verify is set: TLS certificates are NOT verified, API keys and results can be intercepted%s", colorRed, colorReset)
+	}
+
+	if *totalPerDomain < 1 || *totalPerDomain > apiResultCeiling {
+		logger.Error("Invalid -total-per-domain %d, must be between 1 and %d", *totalPerDomain, apiResultCeiling)
+		os.Exit(1)
+	}
+	if *pagesArg < 1 {
+		logger.Error("Invalid -pages %d, must be at least 1", *pagesArg)
+		os.Exit(1)
+	}
+	if *perPageArg < 1 || *perPageArg > 10 {
+		logger.Error("Invalid -per-page %d, must be between 1 and 10", *perPageArg)
+		os.Exit(1)
+	}
+	if (*pagesArg)*(*perPageArg) > apiResultCeiling {
+		logger.Info("-pages %d of %d results is past the API's %d-result limit; fetching at most %d results per query", *pagesArg, *perPageArg, apiResultCeiling, apiResultCeiling)
+	}
+
+	if *maxInflight < 0 {
+		logger.Error("Invalid -max-inflight %d, must be 0 or more", *maxInflight)
+		os.Exit(1)
+	}
+	if *maxInflight > 0 {
+		inflight = newInflightLimit(*maxInflight)
+	} else {
+		inflight = newInflightLimit(*concurrent)
+	}
+
+	if *geoArg != "" {
+		*geoArg = strings.ToLower(*geoArg)
+		if !countryCodeRe.MatchString(*geoArg) {
+			logger.Error("Invalid -geo country code %q, expected a two-letter code such as 'us' or 'de'", *geoArg)
+			os.Exit(1)
+		}
+	}
+
+	if *siteFilter != "" && *siteFilter != "i" && *siteFilter != "e" {
+		logger.Error("Invalid -site-filter %q, expected i (include) or e (exclude)", *siteFilter)
+		os.Exit(1)
+	}
+
+	if *siteFilter != "" && *siteSearch == "" {
+		logger.Error("-site-filter requires -site-search")
+		os.Exit(1)
+	}
+
+	if *fileTypeParam != "" {
+		types := parseFiletypes(*fileTypeParam)
+		if len(types) != 1 {
+			logger.Error("Invalid -file-type %q, expected a single type such as 'pdf'; use -filetype for lists", *fileTypeParam)
+			os.Exit(1)
+		}
+		*fileTypeParam = types[0]
+	}
+
+	switch {
+	case *domainSummaryFD == 1:
+		domainLines = os.Stdout
+	case *domainSummaryFD > 1:
+		domainLines = os.NewFile(uintptr(*domainSummaryFD), fmt.Sprintf("fd%d", *domainSummaryFD))
+	case *domainSummaryFD < 0:
+		logger.Error("Invalid -domain-summary-fd %d, must not be negative", *domainSummaryFD)
+		os.Exit(1)
+	}
+
+	switch *authMode {
+	case "apikey":
+		if *credentialsFile != "" {
+			logger.Error("-credentials requires -auth serviceaccount")
+			os.Exit(1)
+		}
+	case "serviceaccount":
+	default:
+		logger.Error("Unknown -auth %q, expected 'apikey' or 'serviceaccount'", *authMode)
+		os.Exit(1)
+	}
+
+	if *groupBy != "" {
+		if *groupBy != "filetype" {
+			logger.Error("Unknown -group-by %q, the only grouping is 'filetype'", *groupBy)
+			os.Exit(1)
+		}
+		if *subdomains || *urlsOnly {
+			logger.Error("-group-by cannot be used with -subs or -urls")
+			os.Exit(1)
+		}
+	}
+
+	if *minDepth < 0 {
+		logger.Error("Invalid -min-depth %d, must not be negative", *minDepth)
+		os.Exit(1)
+	}
+	if *minDepth > 0 && !*subdomains {
+		logger.Error("-min-depth requires -subs")
+		os.Exit(1)
+	}
+
+	if *probeMeta {
+		if !*subdomains {
+			logger.Error("-probe-meta requires -subs")
+			os.Exit(1)
+		}
+		if !strings.Contains(*formatArg, "txt") && !*detailedJSON {
+			logger.Warn("-probe-meta findings only appear in txt output or with -detailed-json")
+		}
+	}
+
+	if *watchInterval < 0 || (*watchInterval > 0 && *noOverwrite) {
+		logger.Error("-watch needs a positive interval and cannot be used with -no-overwrite")
+		os.Exit(1)
+	}
+
+	if *appendOutput && (*outputArg == "" || *noOverwrite) {
+		logger.Error("-append requires -o and cannot be used with -no-overwrite")
+		os.Exit(1)
+	}
+	if *appendOutput && !*subdomains {
+		for _, format := range outputFormats() {
+			if format != "csv" {
+				logger.Error("-append without -subs only supports -format csv, not %s", format)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if len(outputFormats()) > 1 && *outputArg == "" {
+		logger.Error("Several -format values need -o: they cannot share stdout")
+		os.Exit(1)
+	}
+
+	if *noOverwrite && *outputArg != "" {
+		formats := outputFormats()
+		for _, format := range formats {
+			path := outputPath(*outputArg, format, len(formats) > 1)
+			if _, err := os.Stat(path); err == nil {
+				logger.Error("Output file %s already exists and -no-overwrite is set", path)
+				os.Exit(1)
+			}
+		}
+	}
+
+	var seen *BloomFilter
+	if *bloomFile != "" {
+		var err error
+		seen, err = LoadBloomFilter(*bloomFile, *bloomSize, uint32(*bloomHashes))
+		if err != nil {
+			logger.Error("Failed to load bloom filter: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if *eventsDest != "" {
+		var err error
+		if eventLog, err = openEvents(*eventsDest); err != nil {
+			logger.Error("Failed to open -events: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if *denylistFile != "" {
+		var err error
+		if urlDenylist, err = loadDenylist(*denylistFile); err != nil {
+			logger.Error("Failed to load -url-denylist: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var baseline map[string]time.Time
+	if *firstSeen {
+		baseline = make(map[string]time.Time)
+	}
+	if *baselineFile != "" {
+		var err error
+		if baseline, err = loadBaseline(*baselineFile); err != nil {
+			logger.Error("Failed to load -baseline: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var match *regexp.Regexp
+	if *matchArg != "" {
+		var err error
+		if match, err = regexp.Compile(*matchArg); err != nil {
+			logger.Error("Invalid -match regexp: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var wordlistCharset *regexp.Regexp
+	if *wordlistOut != "" {
+		var err error
+		if wordlistCharset, err = wordlistCharsetRe(*wordlistChars); err != nil {
+			logger.Error("%v", err)
+			os.Exit(1)
+		}
+	}
+
+	if *encryptTo != "" {
+		var err error
+		if outputRecipients, err = loadRecipients(*encryptTo); err != nil {
+			logger.Error("Failed to load -encrypt-to key: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if *templateFile != "" {
+		var err error
+		if outputTemplate, err = loadOutputTemplate(*templateFile); err != nil {
+			logger.Error("Failed to load output template: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var webhookTemplate *template.Template
+	if *webhookTmpl != "" {
+		var err error
+		if webhookTemplate, err = loadWebhookTemplate(*webhookTmpl); err != nil {
+			logger.Error("Failed to load webhook template: %v", err)
+			os.Exit(1)
+		}
+		if *webhookURL == "" {
+			logger.Warn("-webhook-template has no effect without -webhook")
+		}
+	}
+
+	if *jsonlDir != "" {
+		var err error
+		if jsonlStreams, err = newDomainStreams(*jsonlDir); err != nil {
+			logger.Error("Failed to create -output-jsonl-per-domain directory: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var dorks []string
+	if *dorkFile != "" {
+		var err error
+		if dorks, err = loadDorks(*dorkFile); err != nil {
+			logger.Error("Failed to load dorks: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// Credentials on the command line need no config file at all.
+	configFile := ""
+	if !credentialsFromFlags() {
+		configFile = loadConfig()
+	}
+	config := loadAPIConfig(configFile)
+	logger.Debug("Configuration loaded successfully")
+
+	if *printConfig {
+		if err := printEffectiveConfig(os.Stdout, buildEffectiveConfig(configFile, config, flag.CommandLine)); err != nil {
+			logger.Error("Failed to print configuration: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	stopProfile := func() {}
+	if *profileKind != "" {
+		stop, err := startProfile(*profileKind, *profileOut)
+		if err != nil {
+			logger.Error("Failed to start profile: %v", err)
+			os.Exit(1)
+		}
+		stopProfile = func() {
+			if err := stop(); err != nil {
+				logger.Error("Failed to write profile: %v", err)
+			}
+		}
+		defer stopProfile()
+	}
 
 	ctx := context.Background()
-	svc, err := customsearch.NewService(ctx, option.WithAPIKey(googleAPI))
+	pool, err := NewKeyPool(ctx, config, *concurrent)
 	if err != nil {
 		logger.Error("Failed to create custom search service: %v", err)
 		os.Exit(1)
 	}
 
-	domains := getAllDomains()
-	results := processDomains(domains, svc, googleCSEID)
+	if *cacheDir != "" {
+		searchCache = newResponseCache(*cacheDir, *cacheTTL, pool)
+	}
 
-	if *subdomains {
-		outputSubdomains(results)
+	if *listEnginesArg {
+		if !listEngines(ctx, pool, os.Stdout) {
+			os.Exit(1)
+		}
+		return
 	}
 
+	if *redact {
+		enableRedaction()
+	}
+	targets := getAllDomains()
+	if *autoBucket {
+		// One unscoped query; its results are bucketed by domain later.
+		targets = []Target{{Query: *queryArg}}
+	}
+	if dorks != nil {
+		if targets, err = expandDorks(targets, dorks); err != nil {
+			logger.Error("Failed to expand dorks: %v", err)
+			os.Exit(1)
+		}
+	}
+	if types := parseFiletypes(*filetypeArg); len(types) > 0 {
+		if targets, err = applyFiletypes(targets, types, *maxQueryLen); err != nil {
+			logger.Error("%v", err)
+			os.Exit(1)
+		}
+		logger.Debug("Searching for %d file types in %d queries", len(types), len(targets))
+	}
+	targets = splitLongQueries(targets)
+	if *allCSE {
+		if engines := len(distinctEngines(pool)); engines < 2 {
+			logger.Warn("-all-cse has no effect with only %d CSE ID configured", engines)
+		} else {
+			targets = expandEngines(targets, pool)
+			logger.Info("Running every query on %d search engines", engines)
+		}
+	}
+	if *deepPaginate {
+		targets = expandDateWindows(targets, time.Now())
+		logger.Info("Running every query across %d monthly date windows, at up to %d times the quota", deepPaginateMonths, deepPaginateMonths+1)
+	}
+	if *priorityFile != "" {
+		priorities, err := loadPriorities(*priorityFile)
+		if err != nil {
+			logger.Error("Failed to load priorities: %v", err)
+			os.Exit(1)
+		}
+		applyPriorities(targets, priorities)
+	}
+	if *shuffle {
+		rand.Shuffle(len(targets), func(i, j int) {
+			targets[i], targets[j] = targets[j], targets[i]
+		})
+	}
+
+	if *emitCmd != "" {
+		if err := emitCommand(*emitCmd, buildCommand(flag.CommandLine, targets)); err != nil {
+			logger.Error("Failed to write -emit-command: %v", err)
+			os.Exit(1)
+		}
+		if *dryRun {
+			return
+		}
+	}
+
+	if *dryRun {
+		printDryRun(os.Stdout, targets)
+		return
+	}
+
+	if *warmupArg {
+		warmupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := warmup(warmupCtx, pool)
+		cancel()
+		if err != nil {
+			logger.Error("Warmup failed, not starting the run: %v", err)
+			logger.Error("Run with -list-engines to check every key")
+			os.Exit(1)
+		}
+		logger.Info("Warmup query succeeded")
+	}
+
+	if *compareArg {
+		if len(distinctEngines(pool)) < 2 {
+			logger.Error("-compare-engines needs at least two different CSE IDs in the config")
+			os.Exit(1)
+		}
+		compareCtx, cancel := context.WithTimeout(ctx, *timeout)
+		comparisons := compareEngines(compareCtx, pool, targets)
+		cancel()
+
+		var out strings.Builder
+		writeComparison(&out, comparisons, *formatArg)
+		if *outputArg == "" {
+			fmt.Print(out.String())
+		} else if err := writeOutputFile(*outputArg, []byte(out.String())); err != nil {
+			logger.Error("Failed to write output: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	run := runOptions{
+		seen:            seen,
+		baseline:        baseline,
+		match:           match,
+		wordlistCharset: wordlistCharset,
+		webhookTemplate: webhookTemplate,
+	}
+	if *watchInterval > 0 {
+		watchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		watch(watchCtx, pool, targets, run)
+		return
+	}
+	outputErr := runSearch(ctx, pool, targets, run, startTime)
+
 	if !*silent && !*subdomains {
 		duration := time.Since(startTime)
 		logger.Info("%sExecution time: %v%s", colorCyan, duration, colorReset)
 	}
+
+	if outputErr != nil {
+		stopProfile()
+		os.Exit(1)
+	}
 }
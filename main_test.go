@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	var out strings.Builder
+	w := &redactingWriter{w: &out}
+	w.add("Example.COM")
+	w.add("dev.example.com")
+	w.add("")
+
+	w.Write([]byte("Found: https://api.example.com/x on DEV.EXAMPLE.COM and example.org\n"))
+
+	got := out.String()
+	if strings.Contains(strings.ToLower(got), "example.com") {
+		t.Errorf("domain leaked in %q", got)
+	}
+	want := "Found: https://api." + redactDomain("example.com") + "/x on " + redactDomain("dev.example.com") + " and example.org\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractSubdomains(t *testing.T) {
+	setupLogger()
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://api.example.com/x", "api.example.com"},
+		{"https://API.Example.com:8443/", "api.example.com"},
+		{"https://example.com/", ""},
+		{"https://notexample.com/", ""},
+	}
+
+	for _, tt := range tests {
+		subs := extractSubdomains("example.com", tt.link)
+		got := strings.Join(subs, ",")
+		if got != tt.want {
+			t.Errorf("extractSubdomains(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+		if host := hostOf(tt.link); host == "" {
+			t.Errorf("hostOf(%q) is empty", tt.link)
+		}
+	}
+}
+
+func TestExtractSubdomainsCollapseWWW(t *testing.T) {
+	setupLogger()
+	*collapseWWW = true
+	defer func() { *collapseWWW = false }()
+
+	tests := map[string]string{
+		"https://www.dev.example.com/": "dev.example.com",
+		"https://dev.example.com/":     "dev.example.com",
+		"https://www.example.com/":     "www.example.com",
+		"https://www2.example.com/":    "www2.example.com",
+	}
+	for link, want := range tests {
+		if got := strings.Join(extractSubdomains("example.com", link), ","); got != want {
+			t.Errorf("extractSubdomains(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestParseConfigDuplicateKeys(t *testing.T) {
+	setupLogger()
+	var out strings.Builder
+	logger.SetOutput(&out)
+
+	config, err := parseConfig([]byte(`
+Google-API:
+  - key-one
+Google-CSE-ID:
+  - cx-one
+Google-API:
+  - key-two
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(config.GoogleAPI, ",") != "key-one,key-two" {
+		t.Errorf("GoogleAPI = %v, want both keys", config.GoogleAPI)
+	}
+	if strings.Join(config.GoogleCSEID, ",") != "cx-one" {
+		t.Errorf("GoogleCSEID = %v", config.GoogleCSEID)
+	}
+	if !strings.Contains(out.String(), `"Google-API" is defined more than once`) {
+		t.Errorf("no duplicate key warning in %q", out.String())
+	}
+}
+
+func TestLoadAPIConfigEnvCredentials(t *testing.T) {
+	setupLogger()
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.yaml")
+	os.WriteFile(broken, []byte("Google-API: [unterminated\n"), 0600)
+	valid := filepath.Join(dir, "valid.yaml")
+	os.WriteFile(valid, []byte("Google-API: [file-key]\nGoogle-CSE-ID: [file-cx]\n"), 0600)
+
+	t.Setenv(envAPIKey, "env-key-1, env-key-2")
+	t.Setenv(envCSEID, "env-cx")
+	if config := loadAPIConfig(broken); strings.Join(config.GoogleAPI, ",") != "env-key-1,env-key-2" || strings.Join(config.GoogleCSEID, ",") != "env-cx" {
+		t.Errorf("broken config with env credentials = %+v", config)
+	}
+
+	t.Setenv(envAPIKey, "")
+	if config := loadAPIConfig(valid); strings.Join(config.GoogleAPI, ",") != "file-key" || strings.Join(config.GoogleCSEID, ",") != "env-cx" {
+		t.Errorf("env CSE ID should override only the file's CSE IDs: %+v", config)
+	}
+}
+
+func TestWriteAllFormatsContinuesPastFailure(t *testing.T) {
+	setupLogger()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	*outputArg, *formatArg = filepath.Join(dir, "scan.txt"), "json, csv,txt"
+	defer func() { *outputArg, *formatArg = "", "txt" }()
+
+	var written []string
+	err := writeAllFormats(func(out outputTarget) error {
+		if out.format == "json" {
+			return errors.New("disk full")
+		}
+		written = append(written, out.format+":"+filepath.Base(out.path))
+		return nil
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Errorf("err = %v, want 1 of 3 formats failed", err)
+	}
+	if strings.Join(written, ",") != "csv:scan.csv,txt:scan.txt" {
+		t.Errorf("written = %v, want scan.csv and scan.txt", written)
+	}
+	if *outputArg != filepath.Join(dir, "scan.txt") || *formatArg != "json, csv,txt" {
+		t.Errorf("flags changed: -o %q -format %q", *outputArg, *formatArg)
+	}
+}
+
+func TestOnlyWithSubdomains(t *testing.T) {
+	setupLogger()
+	*onlyWithSubs = true
+	defer func() { *onlyWithSubs = false }()
+	out := outputTarget{format: "csv", path: filepath.Join(t.TempDir(), "subs.csv")}
+
+	err := outputSubdomains(map[string]SearchResult{
+		"example.com": {Domain: "example.com", Subdomains: []string{"www.example.com"}},
+		"example.org": {Domain: "example.org"},
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(out.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "Domain,Subdomain\nexample.com,www.example.com\n" {
+		t.Errorf("output = %q, want only example.com", got)
+	}
+}
+
+func TestFlatSubdomains(t *testing.T) {
+	setupLogger()
+	*flatSubs = true
+	defer func() { *flatSubs = false }()
+	out := outputTarget{format: "txt", path: filepath.Join(t.TempDir(), "subs.txt")}
+
+	err := outputSubdomains(map[string]SearchResult{
+		"example.org":     {Domain: "example.org", Subdomains: []string{"www.example.org", "api.example.org"}},
+		"example.com":     {Domain: "example.com", Subdomains: []string{"dev.sub.example.com", "www.example.com"}},
+		"sub.example.com": {Domain: "sub.example.com", Subdomains: []string{"dev.sub.example.com"}},
+		"example.net":     {Domain: "example.net"},
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(out.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "api.example.org\ndev.sub.example.com\nwww.example.com\nwww.example.org\n"; string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLimitTargets(t *testing.T) {
+	setupLogger()
+	targets := []Target{
+		{Domain: "a.com", Query: "inurl:admin"},
+		{Domain: "a.com", Query: "ext:sql"},
+		{Domain: "b.com"},
+		{Domain: "c.com"},
+		{Domain: "a.com", Query: "ext:env"},
+	}
+	var got []string
+	for _, target := range limitTargets(targets, 2) {
+		got = append(got, target.Domain+" "+target.Query)
+	}
+	want := "a.com inurl:admin,a.com ext:sql,b.com ,a.com ext:env"
+	if strings.Join(got, ",") != want {
+		t.Errorf("limitTargets = %q, want %q", strings.Join(got, ","), want)
+	}
+	if kept := limitTargets(targets, 10); len(kept) != len(targets) {
+		t.Errorf("a limit above the domain count kept %d of %d targets", len(kept), len(targets))
+	}
+}
+
+func TestGetAllDomainsSkipsDuplicates(t *testing.T) {
+	setupLogger()
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	os.WriteFile(path, []byte("example.com\nb.com\nexample.com | ext:sql\nEXAMPLE.com.\nb.com\n"), 0644)
+	defer func(domain, list string) { *domainArg, *domainList = domain, list }(*domainArg, *domainList)
+	*domainArg, *domainList = "https://example.com/", path
+
+	var got []string
+	for _, target := range getAllDomains() {
+		got = append(got, target.Domain+" "+target.Query)
+	}
+	want := "example.com ,b.com ,example.com ext:sql"
+	if strings.Join(got, ",") != want {
+		t.Errorf("getAllDomains = %q, want %q", strings.Join(got, ","), want)
+	}
+}
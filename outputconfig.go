@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputConfig holds output defaults from the config file:
+//
+//	Output:
+//	  format: json
+//	  file: results.json
+//
+// Flags given on the command line override them.
+type OutputConfig struct {
+	Format string `yaml:"format,omitempty"`
+	File   string `yaml:"file,omitempty"`
+}
+
+// configFlags records the flags whose value came from the config file, so
+// -print-config can tell them apart from defaults.
+var configFlags = make(map[string]bool)
+
+// readOutputConfig reads only the Output section of the config file at path.
+// It runs before flags are validated, so any problem with the file is left
+// for loadAPIConfig to report.
+func readOutputConfig(path string) OutputConfig {
+	var output OutputConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return output
+	}
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return output
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "Output" {
+			root.Content[i+1].Decode(&output)
+		}
+	}
+	return output
+}
+
+// applyOutputConfig sets -format and -o from the config file unless they
+// were given on the command line.
+func applyOutputConfig(output OutputConfig, flags *flag.FlagSet) {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, value := range map[string]string{"format": output.Format, "o": output.File} {
+		if value == "" || set[name] {
+			continue
+		}
+		flags.Lookup(name).Value.Set(value)
+		configFlags[name] = true
+		logger.Debug("Using -%s %s from the config file", name, value)
+	}
+}
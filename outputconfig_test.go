@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputConfig(t *testing.T) {
+	setupLogger()
+	path := filepath.Join(t.TempDir(), "google_dorker.yaml")
+	os.WriteFile(path, []byte("Google-API:\n  - key\nGoogle-API:\n  - other\nGoogle-CSE-ID:\n  - cx\nOutput:\n  format: json\n  file: results.json\n"), 0600)
+
+	output := readOutputConfig(path)
+	if output != (OutputConfig{Format: "json", File: "results.json"}) {
+		t.Fatalf("readOutputConfig = %+v", output)
+	}
+
+	flags := flag.NewFlagSet("go-dork-google", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "txt", "")
+	file := flags.String("o", "", "")
+	if err := flags.Parse([]string{"-format", "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { delete(configFlags, "o"); delete(configFlags, "format") }()
+
+	applyOutputConfig(output, flags)
+	if *format != "csv" || *file != "results.json" {
+		t.Errorf("format %q, file %q; want the command line's csv and the config's results.json", *format, *file)
+	}
+	if !configFlags["o"] || configFlags["format"] {
+		t.Errorf("configFlags = %v, want only o", configFlags)
+	}
+
+	if output := readOutputConfig(filepath.Join(t.TempDir(), "missing.yaml")); output != (OutputConfig{}) {
+		t.Errorf("missing file gave %+v", output)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+var paramNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]*$`)
+
+// reservedParams are set by the tool itself; overriding them would break
+// pagination, authentication or response parsing.
+var reservedParams = map[string]bool{
+	"key": true, "cx": true, "q": true, "start": true, "num": true,
+	"alt": true, "fields": true, "prettyPrint": true, "callback": true,
+}
+
+// paramFlags collects repeated -param key=value flags. A key given more
+// than once is sent with every value.
+type paramFlags map[string][]string
+
+var extraParams = paramFlags{}
+
+func init() {
+	flag.Var(extraParams, "param", "Advanced: add a raw Custom Search API query parameter as key=value (repeatable)")
+}
+
+func (p paramFlags) String() string {
+	return strings.Join(p.flagValues(), ",")
+}
+
+// flagValues returns each parameter as the key=value given to -param.
+func (p paramFlags) flagValues() []string {
+	var pairs []string
+	for key, values := range p {
+		for _, value := range values {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+func (p paramFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	if !paramNameRe.MatchString(key) {
+		return fmt.Errorf("invalid parameter name %q", key)
+	}
+	if reservedParams[key] {
+		return fmt.Errorf("parameter %q is set by go-dork-google and cannot be overridden", key)
+	}
+	if strings.ContainsAny(value, "\x00\r\n") {
+		return fmt.Errorf("parameter %q contains control characters", key)
+	}
+	p[key] = append(p[key], value)
+	return nil
+}
+
+// callOptions turns the parameters into options for CseListCall.Do.
+func (p paramFlags) callOptions() []googleapi.CallOption {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	opts := make([]googleapi.CallOption, 0, len(keys))
+	for _, key := range keys {
+		opts = append(opts, googleapi.QueryParameter(key, p[key]...))
+	}
+	return opts
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const pausePollInterval = time.Second
+
+var paused atomic.Bool
+
+// searchTimeout is the -timeout of the running search. Time spent paused
+// does not count against it.
+var searchTimeout *pausableTimeout
+
+// pausableTimeout cancels a context once its duration has passed, leaving
+// out the time between pause and resume.
+type pausableTimeout struct {
+	mu        sync.Mutex
+	timer     *time.Timer
+	deadline  time.Time
+	remaining time.Duration
+	stopped   bool
+}
+
+// withPausableTimeout is context.WithTimeout with a deadline that
+// -pause-file can hold. A context it ends reports context.DeadlineExceeded
+// as its cause.
+func withPausableTimeout(parent context.Context, d time.Duration) (context.Context, *pausableTimeout, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	t := &pausableTimeout{deadline: time.Now().Add(d)}
+	t.timer = time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) })
+	return ctx, t, func() {
+		t.timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// pause stops the clock. It does nothing if the timeout already fired or is
+// already paused.
+func (t *pausableTimeout) pause() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.stopped && t.timer.Stop() {
+		t.remaining = time.Until(t.deadline)
+		t.stopped = true
+	}
+}
+
+// resume restarts the clock with the time that was left when it paused.
+func (t *pausableTimeout) resume() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		t.deadline = time.Now().Add(t.remaining)
+		t.timer.Reset(t.remaining)
+		t.stopped = false
+	}
+}
+
+// waitWhilePaused blocks while the -pause-file exists, with searchTimeout
+// held. Requests already in flight are unaffected; workers only stop before
+// starting a new one.
+func waitWhilePaused(ctx context.Context) error {
+	if *pauseFile == "" {
+		return nil
+	}
+
+	for {
+		if _, err := os.Stat(*pauseFile); err != nil {
+			searchTimeout.resume()
+			if paused.CompareAndSwap(true, false) {
+				logger.Info("Pause file removed, resuming")
+			}
+			return nil
+		}
+		searchTimeout.pause()
+		if paused.CompareAndSwap(false, true) {
+			logger.Info("Pause file %s present, pausing new requests", *pauseFile)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pausePollInterval):
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitWhilePaused(t *testing.T) {
+	setupLogger()
+	path := filepath.Join(t.TempDir(), "pause")
+	*pauseFile = path
+	defer func() { *pauseFile = "" }()
+
+	if err := waitWhilePaused(context.Background()); err != nil {
+		t.Fatalf("no pause file: %v", err)
+	}
+
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := waitWhilePaused(ctx); err == nil {
+		t.Fatal("returned while the pause file exists")
+	}
+
+	done := make(chan error)
+	go func() { done <- waitWhilePaused(context.Background()) }()
+	os.Remove(path)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * pausePollInterval):
+		t.Fatal("did not resume after the pause file was removed")
+	}
+}
+
+func TestPauseHoldsTimeout(t *testing.T) {
+	setupLogger()
+	path := filepath.Join(t.TempDir(), "pause")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	*pauseFile = path
+	ctx, deadline, cancel := withPausableTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	searchTimeout = deadline
+	defer func() { *pauseFile, searchTimeout = "", nil }()
+
+	done := make(chan error)
+	go func() { done <- waitWhilePaused(ctx) }()
+	// Paused for well past the timeout.
+	time.Sleep(500 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatal("the timeout ran while paused")
+	}
+	os.Remove(path)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+	case <-time.After(3 * pausePollInterval):
+		t.Fatal("did not resume after the pause file was removed")
+	}
+
+	select {
+	case <-ctx.Done():
+		if !errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+			t.Errorf("cause = %v, want the deadline", context.Cause(ctx))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the timeout did not fire after resuming")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxDorkFilename = 100
+
+var unsafeDorkRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// dorkFilename turns a dork into a readable, safe file name: operators and
+// quotes become "_", so `filetype:log intext:"password"` becomes
+// "filetype_log_intext_password". Long names are cut short and given a hash
+// of the dork so they stay distinct.
+func dorkFilename(dork string) string {
+	name := strings.Trim(unsafeDorkRe.ReplaceAllString(dork, "_"), "._")
+	if name == "" {
+		return "site"
+	}
+	if len(name) > maxDorkFilename {
+		name = name[:maxDorkFilename] + "-" + dorkHash(dork)
+	}
+	return name
+}
+
+func dorkHash(dork string) string {
+	sum := sha256.Sum256([]byte(dork))
+	return hex.EncodeToString(sum[:4])
+}
+
+// writePerDork writes the results of each dork, across all domains, to
+// <dir>/<dork>.<format> for every -format. Dorks whose names collide get
+// their hash appended.
+func writePerDork(dir string, results []Result, searches map[string]SearchResult) error {
+	groups := make(map[string][]Result)
+	for _, result := range results {
+		groups[result.Dork] = append(groups[result.Dork], result)
+	}
+	dorks := make([]string, 0, len(groups))
+	for dork := range groups {
+		dorks = append(dorks, dork)
+	}
+	sort.Strings(dorks)
+
+	if err := os.MkdirAll(dir, outputDirMode()); err != nil {
+		return err
+	}
+
+	formats := outputFormats()
+
+	used := make(map[string]bool)
+	failed := 0
+	for _, dork := range dorks {
+		name := dorkFilename(dork)
+		if used[name] {
+			name += "-" + dorkHash(dork)
+		}
+		used[name] = true
+
+		for _, format := range formats {
+			out := outputTarget{format: format, path: filepath.Join(dir, name+"."+format)}
+			if err := outputResults(groups[dork], searches, out); err != nil {
+				logger.Error("Failed to write %s: %v", out.path, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d per-dork files failed", failed)
+	}
+	logger.Debug("Wrote results of %d dorks to %s", len(dorks), dir)
+	return nil
+}
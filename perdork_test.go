@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDorkFilename(t *testing.T) {
+	tests := map[string]string{
+		`filetype:log intext:"password"`: "filetype_log_intext_password",
+		"inurl:admin/../login":           "inurl_admin_.._login",
+		"":                               "site",
+		`""`:                             "site",
+	}
+	for dork, want := range tests {
+		if got := dorkFilename(dork); got != want {
+			t.Errorf("dorkFilename(%q) = %q, want %q", dork, got, want)
+		}
+	}
+	if long := dorkFilename(strings.Repeat("intext:x ", 30)); len(long) != maxDorkFilename+9 {
+		t.Errorf("long name %q not shortened", long)
+	}
+}
+
+func TestWritePerDork(t *testing.T) {
+	setupLogger()
+	*formatArg = "txt,csv"
+	defer func() { *formatArg = "txt" }()
+
+	dir := filepath.Join(t.TempDir(), "by-dork")
+	results := []Result{
+		{Domain: "example.com", URL: "https://example.com/a.log", Title: "a", Dork: "filetype:log"},
+		{Domain: "example.org", URL: "https://example.org/b.log", Title: "b", Dork: "filetype:log"},
+		{Domain: "example.com", URL: "https://example.com/admin", Title: "admin", Dork: "inurl:admin"},
+		{Domain: "example.com", URL: "https://example.com/x", Title: "x", Dork: "inurl admin"},
+	}
+	if err := writePerDork(dir, results, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := "filetype_log.csv,filetype_log.txt,inurl_admin-" + dorkHash("inurl:admin") + ".csv,inurl_admin-" +
+		dorkHash("inurl:admin") + ".txt,inurl_admin.csv,inurl_admin.txt"
+	if strings.Join(names, ",") != want {
+		t.Fatalf("files = %v, want %s", names, want)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "filetype_log.txt"))
+	if !strings.Contains(string(data), "https://example.com/a.log") || !strings.Contains(string(data), "https://example.org/b.log") {
+		t.Errorf("filetype_log.txt = %s, want results of both domains", data)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// nonStandardPort returns the host and port of a URL that names a port other
+// than its scheme's default, such as https://example.com:8443/.
+func nonStandardPort(link string) (host, port string, ok bool) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", "", false
+	}
+	port = parsed.Port()
+	if port == "" || port == defaultPorts[strings.ToLower(parsed.Scheme)] {
+		return "", "", false
+	}
+	return strings.ToLower(parsed.Hostname()), port, true
+}
+
+// hostPorts collects the distinct host:port pairs on non-standard ports per
+// domain, sorted.
+func hostPorts(results []Result) map[string][]string {
+	seen := make(map[string]bool)
+	ports := make(map[string][]string)
+	for _, result := range results {
+		host, port, ok := nonStandardPort(result.URL)
+		if !ok {
+			continue
+		}
+		hostPort := net.JoinHostPort(host, port)
+		if key := result.Domain + "\x00" + hostPort; !seen[key] {
+			seen[key] = true
+			ports[result.Domain] = append(ports[result.Domain], hostPort)
+		}
+	}
+	for _, list := range ports {
+		sort.Strings(list)
+	}
+	return ports
+}
+
+func outputPorts(results []Result, out outputTarget) error {
+	ports := hostPorts(results)
+	domains := make([]string, 0, len(ports))
+	for domain := range ports {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	var output strings.Builder
+	switch out.format {
+	case "json":
+		data, err := marshalOutput(ports)
+		if err != nil {
+			return err
+		}
+		output.Write(data)
+		output.WriteString("\n")
+	case "csv":
+		writer := csv.NewWriter(&output)
+		writer.Write([]string{"Domain", "Host", "Port"})
+		for _, domain := range domains {
+			for _, hostPort := range ports[domain] {
+				host, port, _ := net.SplitHostPort(hostPort)
+				writer.Write([]string{domain, host, port})
+			}
+		}
+		writer.Flush()
+	default:
+		for _, domain := range domains {
+			for _, hostPort := range ports[domain] {
+				fmt.Fprintln(&output, hostPort)
+			}
+		}
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNonStandardPort(t *testing.T) {
+	tests := []struct {
+		link, host, port string
+		ok               bool
+	}{
+		{"https://admin.example.com:8443/login", "admin.example.com", "8443", true},
+		{"http://Dev.Example.com:8080/", "dev.example.com", "8080", true},
+		{"http://example.com:443/", "example.com", "443", true},
+		{"https://example.com:443/", "", "", false},
+		{"http://example.com:80/", "", "", false},
+		{"https://example.com/", "", "", false},
+		{"https://[::1]:9000/", "::1", "9000", true},
+	}
+	for _, tt := range tests {
+		host, port, ok := nonStandardPort(tt.link)
+		if host != tt.host || port != tt.port || ok != tt.ok {
+			t.Errorf("nonStandardPort(%q) = %q, %q, %v; want %q, %q, %v", tt.link, host, port, ok, tt.host, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestHostPorts(t *testing.T) {
+	results := []Result{
+		{Domain: "example.com", URL: "https://b.example.com:8443/a"},
+		{Domain: "example.com", URL: "https://b.example.com:8443/b"},
+		{Domain: "example.com", URL: "http://a.example.com:8080/"},
+		{Domain: "example.com", URL: "https://example.com/"},
+		{Domain: "example.org", URL: "https://example.org/"},
+	}
+	want := map[string][]string{"example.com": {"a.example.com:8080", "b.example.com:8443"}}
+	if got := hostPorts(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostPorts = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EffectiveConfig is the fully resolved configuration printed by
+// -print-config. API keys and other secrets are masked.
+type EffectiveConfig struct {
+	ConfigFile string            `yaml:"config_file,omitempty"`
+	Engines    []EffectiveEngine `yaml:"engines"`
+	Flags      []EffectiveFlag   `yaml:"flags"`
+}
+
+type EffectiveEngine struct {
+	APIKey string `yaml:"api_key,omitempty"`
+	CSEID  string `yaml:"cse_id"`
+}
+
+type EffectiveFlag struct {
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value"`
+	Source string `yaml:"source"`
+}
+
+// buildEffectiveConfig pairs keys with CSE IDs the same way NewKeyPool does
+// and records every flag value along with whether it was set on the command
+// line or left at its default. Flag values are redacted the same way as by
+// -emit-command.
+func buildEffectiveConfig(path string, config Config, flags *flag.FlagSet) EffectiveConfig {
+	effective := EffectiveConfig{ConfigFile: path}
+
+	pairs := len(config.GoogleAPI)
+	if *authMode != "apikey" || len(config.GoogleCSEID) > pairs {
+		pairs = len(config.GoogleCSEID)
+	}
+	for i := 0; i < pairs && len(config.GoogleCSEID) > 0; i++ {
+		engine := EffectiveEngine{CSEID: config.GoogleCSEID[i%len(config.GoogleCSEID)]}
+		if len(config.GoogleAPI) > 0 && *authMode == "apikey" {
+			engine.APIKey = maskKey(config.GoogleAPI[i%len(config.GoogleAPI)])
+		}
+		effective.Engines = append(effective.Engines, engine)
+	}
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	flags.VisitAll(func(f *flag.Flag) {
+		source := "default"
+		if set[f.Name] {
+			source = "command line"
+		} else if configFlags[f.Name] {
+			source = "config file"
+		}
+		effective.Flags = append(effective.Flags, EffectiveFlag{
+			Name:   f.Name,
+			Value:  redactFlagValue(f.Name, f.Value.String()),
+			Source: source,
+		})
+	})
+	return effective
+}
+
+func printEffectiveConfig(w io.Writer, effective EffectiveConfig) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(effective); err != nil {
+		return err
+	}
+	return enc.Close()
+}
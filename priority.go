@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// loadPriorities reads "domain priority" lines. Higher numbers are searched
+// first; domains that are not listed have priority 0.
+func loadPriorities(filename string) (map[string]int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	priorities := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected 'domain priority'", filename, lineNo)
+		}
+		priority, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid priority %q", filename, lineNo, fields[1])
+		}
+		priorities[normalizeDomain(fields[0])] = priority
+	}
+	return priorities, scanner.Err()
+}
+
+func applyPriorities(targets []Target, priorities map[string]int) {
+	for i := range targets {
+		targets[i].Priority = priorities[strings.ToLower(targets[i].Domain)]
+	}
+}
+
+type priorityTier struct {
+	Priority int
+	Domains  int
+	Searched int
+}
+
+// priorityTiers counts, for each priority, how many domains there were and
+// how many were searched successfully, highest priority first.
+func priorityTiers(targets []Target, results map[string]SearchResult) []priorityTier {
+	byPriority := make(map[int]*priorityTier)
+	counted := make(map[string]bool)
+	for _, target := range targets {
+		if counted[target.Domain] {
+			continue
+		}
+		counted[target.Domain] = true
+
+		tier := byPriority[target.Priority]
+		if tier == nil {
+			tier = &priorityTier{Priority: target.Priority}
+			byPriority[target.Priority] = tier
+		}
+		tier.Domains++
+		if _, ok := results[target.Domain]; ok {
+			tier.Searched++
+		}
+	}
+
+	tiers := make([]priorityTier, 0, len(byPriority))
+	for _, tier := range byPriority {
+		tiers = append(tiers, *tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Priority > tiers[j].Priority })
+	return tiers
+}
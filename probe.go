@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	maxProbeBody   = 5 << 20
+	maxSitemapURLs = 500
+)
+
+// HostMeta holds the recon hints -probe-meta found on one host: the paths
+// robots.txt disallows and the URLs listed by it or by /sitemap.xml.
+type HostMeta struct {
+	Host     string   `json:"host"`
+	Disallow []string `json:"disallow,omitempty"`
+	Sitemaps []string `json:"sitemaps,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// fetchHostFile gets path from host over HTTPS, falling back to plain HTTP if
+// the host cannot be reached. A missing file is not an error.
+func fetchHostFile(ctx context.Context, client *http.Client, host, path string) ([]byte, error) {
+	if err := inflight.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer inflight.release()
+
+	var lastErr error
+	for _, scheme := range []string{"https", "http"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "go-dork-google/"+VERSION)
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	}
+	return nil, lastErr
+}
+
+func parseRobots(data []byte) (disallow, sitemaps []string) {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "disallow":
+			disallow = append(disallow, value)
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+	}
+	return disallow, sitemaps
+}
+
+// parseSitemap returns the <loc> entries of a sitemap or sitemap index.
+func parseSitemap(data []byte) []string {
+	var doc struct {
+		URLs     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if xml.Unmarshal(data, &doc) != nil {
+		return nil
+	}
+	locs := append(doc.Sitemaps, doc.URLs...)
+	if len(locs) > maxSitemapURLs {
+		locs = locs[:maxSitemapURLs]
+	}
+	for i := range locs {
+		locs[i] = strings.TrimSpace(locs[i])
+	}
+	return locs
+}
+
+func probeHost(ctx context.Context, client *http.Client, host string) HostMeta {
+	meta := HostMeta{Host: host}
+	robots, err := fetchHostFile(ctx, client, host, "/robots.txt")
+	if err != nil {
+		meta.Error = err.Error()
+		return meta
+	}
+	meta.Disallow, meta.Sitemaps = parseRobots(robots)
+
+	sitemap, err := fetchHostFile(ctx, client, host, "/sitemap.xml")
+	if err == nil {
+		meta.Sitemaps = unionSorted(meta.Sitemaps, parseSitemap(sitemap))
+	}
+	meta.Disallow = unionSorted(meta.Disallow, nil)
+	return meta
+}
+
+// probeHosts probes every host from at most concurrency workers.
+func probeHosts(ctx context.Context, client *http.Client, hosts []string, concurrency int) map[string]HostMeta {
+	var mu sync.Mutex
+	metas := make(map[string]HostMeta, len(hosts))
+	forEachBounded(hosts, concurrency, func(host string) {
+		meta := probeHost(ctx, client, host)
+		logger.Debug("Probed %s: %d disallowed paths, %d sitemap URLs", host, len(meta.Disallow), len(meta.Sitemaps))
+		mu.Lock()
+		metas[host] = meta
+		mu.Unlock()
+	})
+	return metas
+}
+
+// attachHostMeta probes every subdomain in results and stores what was found
+// on each domain's result.
+func attachHostMeta(ctx context.Context, client *http.Client, results map[string]SearchResult, concurrency int) {
+	var hosts []string
+	for _, result := range results {
+		hosts = append(hosts, result.Subdomains...)
+	}
+	hosts = unionSorted(hosts, nil)
+	logger.Info("Probing %d hosts for robots.txt and sitemap.xml", len(hosts))
+	metas := probeHosts(ctx, client, hosts, concurrency)
+
+	for domain, result := range results {
+		result.Meta = nil
+		for _, sub := range result.Subdomains {
+			if meta := metas[sub]; len(meta.Disallow) > 0 || len(meta.Sitemaps) > 0 || meta.Error != "" {
+				result.Meta = append(result.Meta, meta)
+			}
+		}
+		sort.Slice(result.Meta, func(i, j int) bool { return result.Meta[i].Host < result.Meta[j].Host })
+		results[domain] = result
+	}
+}
+
+func formatHostMeta(meta HostMeta) string {
+	var out strings.Builder
+	for _, path := range meta.Disallow {
+		fmt.Fprintf(&out, "  disallow: %s\n", path)
+	}
+	for _, u := range meta.Sitemaps {
+		fmt.Fprintf(&out, "  sitemap: %s\n", u)
+	}
+	return out.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachHostMeta(t *testing.T) {
+	setupLogger()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /admin # staff only\nDisallow: /backup\nDisallow:\nSitemap: http://example.com/extra.xml\n"))
+		case "/sitemap.xml":
+			w.Write([]byte(`<?xml version="1.0"?><urlset><url><loc> http://example.com/a </loc></url><url><loc>http://example.com/b</loc></url></urlset>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	results := map[string]SearchResult{
+		"example.com": {Domain: "example.com", Subdomains: []string{host}},
+	}
+	attachHostMeta(context.Background(), &http.Client{Timeout: 5 * time.Second}, results, 2)
+
+	meta := results["example.com"].Meta
+	if len(meta) != 1 {
+		t.Fatalf("meta = %+v, want one host", meta)
+	}
+	if got := strings.Join(meta[0].Disallow, ","); got != "/admin,/backup" {
+		t.Errorf("disallow = %s", got)
+	}
+	if got := strings.Join(meta[0].Sitemaps, ","); got != "http://example.com/a,http://example.com/b,http://example.com/extra.xml" {
+		t.Errorf("sitemaps = %s", got)
+	}
+}
+
+func TestParseSubdomainsTXTSkipsProbeMeta(t *testing.T) {
+	data := []byte("www.example.com\n  disallow: /admin\n  sitemap: http://www.example.com/a\n")
+	subs, err := parseSubdomainsTXT(data, map[string]SearchResult{"example.com": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(subs["example.com"], ","); got != "www.example.com" {
+		t.Errorf("subdomains = %s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfile begins a -profile of the given kind written to path, or to
+// cpu.pprof or mem.pprof when path is empty. A CPU profile is sampled until
+// the returned stop is called; a memory profile is a heap snapshot taken by
+// stop.
+func startProfile(kind, path string) (stop func() error, err error) {
+	if kind != "cpu" && kind != "mem" {
+		return nil, fmt.Errorf("unknown -profile %q, must be cpu or mem", kind)
+	}
+	if path == "" {
+		path = kind + ".pprof"
+	}
+	file, err := openOutput(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "cpu" {
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		}, nil
+	}
+	return func() error {
+		runtime.GC() // up-to-date statistics for the snapshot
+		if err := pprof.WriteHeapProfile(file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
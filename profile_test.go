@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfile(t *testing.T) {
+	for _, kind := range []string{"cpu", "mem"} {
+		path := filepath.Join(t.TempDir(), kind+".pprof")
+		stop, err := startProfile(kind, path)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if err := stop(); err != nil {
+			t.Fatalf("%s stop: %v", kind, err)
+		}
+		// pprof files are gzipped protocol buffers.
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gzip.NewReader(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s profile is not a pprof file: %v", kind, err)
+		}
+	}
+
+	if _, err := startProfile("block", ""); err == nil {
+		t.Error("accepted -profile block")
+	}
+}
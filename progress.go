@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sync"
+)
+
+// progressTracker counts finished domains. A domain searched several times,
+// as with -dorks, -all-cse or -deep-paginate, counts once, when its last
+// search finishes, and as failed if any of them failed. In -quiet-progress
+// mode it logs only at milestones: every -progress-every domains, or every
+// 10% of the run when that is unset.
+type progressTracker struct {
+	mu        sync.Mutex
+	total     int
+	completed int
+	failed    int
+	results   int
+	lastStep  int
+	pending   map[string]int
+	erred     map[string]bool
+	failures  map[ErrorKind]int
+	errors    []SearchResult
+}
+
+var progress = &progressTracker{}
+
+func (p *progressTracker) start(targets []Target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = make(map[string]int)
+	for _, target := range targets {
+		p.pending[target.Domain]++
+	}
+	p.erred = make(map[string]bool)
+	p.total = len(p.pending)
+	p.completed, p.failed, p.results, p.lastStep = 0, 0, 0, 0
+	p.failures = make(map[ErrorKind]int)
+	p.errors = nil
+}
+
+func (p *progressTracker) done(result SearchResult, results int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.results += results
+	if result.Error != "" {
+		p.erred[result.Domain] = true
+		kind := result.ErrorKind
+		if kind == "" {
+			kind = ErrorUnknown
+		}
+		p.failures[kind]++
+		p.errors = append(p.errors, SearchResult{Domain: result.Domain, Error: result.Error, ErrorKind: kind})
+	}
+	if p.pending[result.Domain] > 1 {
+		p.pending[result.Domain]--
+		return
+	}
+	delete(p.pending, result.Domain)
+	p.completed++
+	if p.erred[result.Domain] {
+		p.failed++
+	}
+
+	if !*quietProgress {
+		logger.Debug("Finished domain %s (%d/%d)", result.Domain, p.completed, p.total)
+		return
+	}
+
+	interval := *progressEvery
+	if interval <= 0 {
+		interval = (p.total + 9) / 10
+	}
+	if step := p.completed / interval; step > p.lastStep || p.completed == p.total {
+		p.lastStep = step
+		logger.Info("Progress: %d/%d domains (%d%%), %d results, %d failed",
+			p.completed, p.total, 100*p.completed/p.total, p.results, p.failed)
+	}
+}
+
+func (p *progressTracker) counts() (completed, failed, results int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.completed, p.failed, p.results
+}
+
+// failedSearches returns the failed searches in the order they finished.
+func (p *progressTracker) failedSearches() []SearchResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SearchResult(nil), p.errors...)
+}
+
+// failureKinds counts the failed searches by ErrorKind.
+func (p *progressTracker) failureKinds() map[ErrorKind]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	kinds := make(map[ErrorKind]int, len(p.failures))
+	for kind, n := range p.failures {
+		kinds[kind] = n
+	}
+	return kinds
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProgressMilestones(t *testing.T) {
+	tests := []struct {
+		total, every, want int
+	}{
+		{20, 0, 10},
+		{25, 0, 9},
+		{7, 0, 7},
+		{10, 4, 3},
+	}
+
+	for _, tt := range tests {
+		setupLogger()
+		var out strings.Builder
+		logger.SetOutput(&out)
+		*quietProgress, *progressEvery = true, tt.every
+
+		tracker := &progressTracker{}
+		var targets []Target
+		for i := 0; i < tt.total; i++ {
+			targets = append(targets, Target{Domain: fmt.Sprintf("d%d.com", i)})
+		}
+		tracker.start(targets)
+		for _, target := range targets {
+			tracker.done(SearchResult{Domain: target.Domain}, 1)
+		}
+
+		if got := strings.Count(out.String(), "Progress:"); got != tt.want {
+			t.Errorf("total=%d every=%d: %d milestones, want %d", tt.total, tt.every, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "100%") {
+			t.Errorf("total=%d every=%d: final milestone missing", tt.total, tt.every)
+		}
+	}
+	*quietProgress, *progressEvery = false, 0
+}
+
+func TestProgressCountsDomains(t *testing.T) {
+	setupLogger()
+	var out strings.Builder
+	logger.SetOutput(&out)
+	*quietProgress = true
+	defer func() { *quietProgress = false }()
+
+	// Three dorks on a.com and two on b.com are two domains, not five.
+	tracker := &progressTracker{}
+	tracker.start([]Target{
+		{Domain: "a.com", Query: "ext:pdf"}, {Domain: "a.com", Query: "ext:sql"}, {Domain: "a.com", Query: "inurl:admin"},
+		{Domain: "b.com", Query: "ext:pdf"}, {Domain: "b.com", Query: "ext:sql"},
+	})
+	tracker.done(SearchResult{Domain: "a.com"}, 2)
+	tracker.done(SearchResult{Domain: "b.com", Error: "bad request", ErrorKind: ErrorBadRequest}, 0)
+	tracker.done(SearchResult{Domain: "a.com"}, 1)
+	if completed, _, _ := tracker.counts(); completed != 0 {
+		t.Errorf("%d domains completed with searches still pending, want 0", completed)
+	}
+	tracker.done(SearchResult{Domain: "b.com"}, 4)
+	tracker.done(SearchResult{Domain: "a.com"}, 0)
+
+	completed, failed, results := tracker.counts()
+	if completed != 2 || failed != 1 || results != 7 {
+		t.Errorf("counts = %d completed, %d failed, %d results; want 2, 1, 7", completed, failed, results)
+	}
+	if !strings.Contains(out.String(), "Progress: 2/2 domains (100%)") {
+		t.Errorf("final milestone should count domains:\n%s", out.String())
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+)
+
+// maxQueryWords is how many terms Google honours in a query; anything past
+// it is silently ignored.
+const maxQueryWords = 32
+
+// queryWords counts the terms in a query the way they count towards the
+// word limit. A quoted phrase is one unit, and parentheses are not terms.
+func queryWords(query string) int {
+	words := 0
+	inWord, quoted := false, false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			if !inWord {
+				words++
+				inWord = true
+			}
+			quoted = !quoted
+		case quoted:
+		case r == ' ' || r == '\t' || r == '(' || r == ')':
+			inWord = false
+		case !inWord:
+			words++
+			inWord = true
+		}
+	}
+	return words
+}
+
+// orGroup is a parenthesized "a OR b OR c" group in a query.
+type orGroup struct {
+	start, end   int // offsets of the parentheses
+	alternatives []string
+}
+
+// orGroups finds the top-level parenthesized OR groups of a query, ignoring
+// parentheses inside quoted phrases.
+func orGroups(query string) []orGroup {
+	var groups []orGroup
+	depth, open, quoted := 0, 0, false
+	for i, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				if alternatives := splitOR(query[open+1 : i]); len(alternatives) > 1 {
+					groups = append(groups, orGroup{start: open, end: i, alternatives: alternatives})
+				}
+			}
+		}
+	}
+	return groups
+}
+
+// splitOR splits s on OR operators outside quotes and nested parentheses.
+func splitOR(s string) []string {
+	var parts []string
+	depth, last, quoted := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], " OR "):
+			parts = append(parts, strings.TrimSpace(s[last:i]))
+			last = i + len(" OR ")
+			i += len(" OR ") - 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[last:]))
+}
+
+// splitLongQueries splits every query over the word limit across several
+// searches of the same domain, by dividing its largest OR group between
+// them; the results are merged per domain as for -dorks. A query without
+// an OR group to divide is kept as is with a warning, because Google will
+// ignore its trailing terms.
+func splitLongQueries(targets []Target) []Target {
+	var split []Target
+	for _, target := range targets {
+		words := queryWords(constructQuery(target.Domain, target.Query))
+		if words <= maxQueryWords {
+			split = append(split, target)
+			continue
+		}
+
+		parts := splitOnOrGroup(target)
+		if len(parts) == 1 {
+			logger.Warn("Query for %s has %d words; Google ignores terms past %d: %s", target.Domain, words, maxQueryWords, target.Query)
+		} else {
+			logger.Info("Split %d-word query for %s into %d queries to stay within the %d-word limit", words, target.Domain, len(parts), maxQueryWords)
+		}
+		split = append(split, parts...)
+	}
+	return split
+}
+
+func splitOnOrGroup(target Target) []Target {
+	groups := orGroups(target.Query)
+	if len(groups) == 0 {
+		return []Target{target}
+	}
+	group := groups[0]
+	for _, g := range groups[1:] {
+		if len(g.alternatives) > len(group.alternatives) {
+			group = g
+		}
+	}
+
+	prefix, suffix := target.Query[:group.start], target.Query[group.end+1:]
+	build := func(alternatives []string) Target {
+		clause := alternatives[0]
+		if len(alternatives) > 1 {
+			clause = "(" + strings.Join(alternatives, " OR ") + ")"
+		}
+		t := target
+		t.Query = prefix + clause + suffix
+		return t
+	}
+	fits := func(t Target) bool {
+		return queryWords(constructQuery(t.Domain, t.Query)) <= maxQueryWords
+	}
+
+	var parts []Target
+	var chunk []string
+	for _, alternative := range group.alternatives {
+		next := append(append([]string(nil), chunk...), alternative)
+		if len(chunk) > 0 && !fits(build(next)) {
+			parts = append(parts, build(chunk))
+			next = []string{alternative}
+		}
+		chunk = next
+	}
+	parts = append(parts, build(chunk))
+	if len(parts) == 1 {
+		return []Target{target}
+	}
+	return parts
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQueryWords(t *testing.T) {
+	tests := map[string]int{
+		"site:example.com inurl:admin":                2,
+		`intext:"index of" "parent directory" pdf`:    3,
+		"(filetype:pdf OR filetype:doc) confidential": 4,
+		`"a (b) c"`: 1,
+		"":          0,
+	}
+	for query, want := range tests {
+		if got := queryWords(query); got != want {
+			t.Errorf("queryWords(%q) = %d, want %d", query, got, want)
+		}
+	}
+}
+
+func TestSplitLongQueries(t *testing.T) {
+	setupLogger()
+	var terms []string
+	for i := 0; i < 20; i++ {
+		terms = append(terms, fmt.Sprintf("inurl:t%d", i))
+	}
+	long := Target{Domain: "example.com", Query: `"login page" (` + strings.Join(terms, " OR ") + `) -test`, Priority: 3}
+
+	targets := splitLongQueries([]Target{{Domain: "example.org", Query: "inurl:admin"}, long})
+	if len(targets) != 3 || targets[0].Query != "inurl:admin" {
+		t.Fatalf("targets = %+v, want the short query and two halves", targets)
+	}
+
+	var seen []string
+	for _, target := range targets[1:] {
+		query := constructQuery(target.Domain, target.Query)
+		if words := queryWords(query); words > maxQueryWords {
+			t.Errorf("%d words in %s", words, query)
+		}
+		if !strings.HasPrefix(target.Query, `"login page" (`) || !strings.HasSuffix(target.Query, ") -test") || target.Priority != 3 {
+			t.Errorf("split lost the rest of the query: %+v", target)
+		}
+		seen = append(seen, splitOR(target.Query[strings.Index(target.Query, "(")+1:strings.LastIndex(target.Query, ")")])...)
+	}
+	if strings.Join(seen, " OR ") != strings.Join(terms, " OR ") {
+		t.Errorf("alternatives = %v, want all of %v once", seen, terms)
+	}
+
+	words := strings.Repeat("word ", 40)
+	if got := splitLongQueries([]Target{{Domain: "example.com", Query: words}}); len(got) != 1 || got[0].Query != words {
+		t.Errorf("query without an OR group changed: %+v", got)
+	}
+}
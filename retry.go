@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	rateLimitBackoff = 5 * time.Second
+	backendBackoff   = 500 * time.Millisecond
+	maxRetries       = 3
+	maxBackoff       = 2 * time.Minute
+)
+
+func hasReason(apiErr *googleapi.Error, reasons ...string) bool {
+	for _, item := range apiErr.Errors {
+		for _, reason := range reasons {
+			if item.Reason == reason {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryBackoff reports whether err is worth retrying and how long to wait
+// before the given attempt. Rate limiting backs off much longer than
+// transient backend failures, which usually clear within a second. With
+// -retry-429-only, backend failures are not retried at all.
+func retryBackoff(err error, attempt int) (time.Duration, bool) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+
+	var base time.Duration
+	switch {
+	case dailyQuotaExceeded(apiErr):
+		// Waiting does not help before the quota resets at midnight Pacific.
+		return 0, false
+	case apiErr.Code == http.StatusTooManyRequests || hasReason(apiErr, "rateLimitExceeded", "userRateLimitExceeded"):
+		base = rateLimitBackoff
+	case *retry429Only:
+		return 0, false
+	case hasReason(apiErr, "backendError", "internalError") || apiErr.Code >= http.StatusInternalServerError:
+		base = backendBackoff
+	default:
+		return 0, false
+	}
+	backoff := base
+	for i := 0; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff, true
+}
+
+func searchWithRetry(ctx context.Context, req *customsearch.CseListCall, domain string) (*customsearch.Search, error) {
+	for attempt := 0; ; attempt++ {
+		if err := waitForCooldown(ctx); err != nil {
+			return nil, err
+		}
+		if err := inflight.acquire(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := req.Context(ctx).Do(extraParams.callOptions()...)
+		inflight.release()
+		if *verboseTiming {
+			took := time.Since(start)
+			logger.Debug("Page fetch for domain %s took %v (rolling average %v)", domain, took.Round(time.Millisecond), pageLatency.observe(took).Round(time.Millisecond))
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if *throttle429 > 0 && classifyError(err) == ErrorRateLimited {
+			startCooldown(*throttle429)
+		}
+
+		backoff, retryable := retryBackoff(err, attempt)
+		if !retryable || attempt >= maxRetries {
+			return nil, err
+		}
+
+		logger.Debug("Retrying search for domain %s in %v (attempt %d/%d): %v", domain, backoff, attempt+1, maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetry429Only(t *testing.T) {
+	*retry429Only = true
+	defer func() { *retry429Only = false }()
+
+	if _, retryable := retryBackoff(&googleapi.Error{Code: 429}, 0); !retryable {
+		t.Error("rate limit not retried")
+	}
+	if _, retryable := retryBackoff(&googleapi.Error{Code: 503, Errors: []googleapi.ErrorItem{{Reason: "backendError"}}}, 0); retryable {
+		t.Error("backend error retried")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		attempt   int
+		want      time.Duration
+		retryable bool
+	}{
+		{"rate limited", &googleapi.Error{Code: 429}, 0, rateLimitBackoff, true},
+		{"rate limited reason", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, 1, 2 * rateLimitBackoff, true},
+		{"backend error", &googleapi.Error{Code: 500, Errors: []googleapi.ErrorItem{{Reason: "backendError"}}}, 2, 4 * backendBackoff, true},
+		{"capped", &googleapi.Error{Code: 429}, 40, maxBackoff, true},
+		{"queries per day", &googleapi.Error{Code: 429, Message: dailyQuotaMessage, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, 0, 0, false},
+		{"invalid query", &googleapi.Error{Code: 400}, 0, 0, false},
+		{"not an API error", errors.New("dial tcp: timeout"), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, retryable := retryBackoff(tt.err, tt.attempt)
+			if got != tt.want || retryable != tt.retryable {
+				t.Errorf("retryBackoff() = %v, %v; want %v, %v", got, retryable, tt.want, tt.retryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "encoding/json"
+
+// latestSchemaVersion is the newest JSON output layout. Version 1 is the
+// original unversioned document; version 2 wraps it as
+// {"schema_version": 2, "data": ...} so consumers can detect later changes.
+const latestSchemaVersion = 2
+
+type versionedOutput struct {
+	SchemaVersion int `json:"schema_version"`
+	Data          any `json:"data"`
+}
+
+// marshalOutput renders a JSON output document in the layout chosen with
+// -output-schema-version.
+func marshalOutput(doc any) ([]byte, error) {
+	if *schemaVersion >= 2 {
+		doc = versionedOutput{SchemaVersion: *schemaVersion, Data: doc}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
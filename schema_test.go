@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalOutputSchemaVersions(t *testing.T) {
+	defer func() { *schemaVersion = 1 }()
+	subs := map[string][]string{"example.com": {"a.example.com"}}
+
+	*schemaVersion = 1
+	data, err := marshalOutput(subs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain map[string][]string
+	if err := json.Unmarshal(data, &plain); err != nil || !reflect.DeepEqual(plain, subs) {
+		t.Errorf("version 1 = %s, want the bare subdomain map", data)
+	}
+
+	*schemaVersion = 2
+	if data, err = marshalOutput(subs); err != nil {
+		t.Fatal(err)
+	}
+	var versioned struct {
+		SchemaVersion int                 `json:"schema_version"`
+		Data          map[string][]string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &versioned); err != nil || versioned.SchemaVersion != 2 || !reflect.DeepEqual(versioned.Data, subs) {
+		t.Errorf("version 2 = %s, want the map under data with schema_version 2", data)
+	}
+
+	// -append reads back either layout.
+	parsed, err := parseSubdomainsJSON(data)
+	if err != nil || !reflect.DeepEqual(parsed, subs) {
+		t.Errorf("parseSubdomainsJSON(version 2) = %v, %v", parsed, err)
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/option"
+)
+
+type requestLog struct {
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+func (l *requestLog) all() []url.Values {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]url.Values(nil), l.requests...)
+}
+
+// newFakeCSE serves total numbered results for any query, paginated the way
+// the Custom Search API paginates them, and records every request's query
+// parameters.
+func newFakeCSE(t *testing.T, total int) (*KeyPool, *requestLog) {
+	return newFailingCSE(t, total, 0)
+}
+
+// newFailingCSE is newFakeCSE with every page from failFrom onwards
+// rejected as a bad request. A failFrom of 0 never fails.
+func newFailingCSE(t *testing.T, total, failFrom int) (*KeyPool, *requestLog) {
+	t.Helper()
+	log := &requestLog{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.mu.Lock()
+		log.requests = append(log.requests, r.URL.Query())
+		log.mu.Unlock()
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		if failFrom > 0 && start >= failFrom {
+			http.Error(w, `{"error": {"code": 400, "message": "bad page"}}`, http.StatusBadRequest)
+			return
+		}
+		num, _ := strconv.Atoi(r.URL.Query().Get("num"))
+		var items []map[string]string
+		for i := start; i < start+num && i <= total; i++ {
+			items = append(items, map[string]string{
+				"title":   fmt.Sprintf("Result %d", i),
+				"link":    fmt.Sprintf("https://s%d.example.com/page%d", i%3, i),
+				"snippet": "snippet for " + r.URL.Query().Get("q"),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	}))
+	t.Cleanup(server.Close)
+
+	svc, err := customsearch.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := &KeyPool{slots: 1, keys: []*APIKey{{svc: svc, cseID: "cx", name: "test", health: 1}}}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool, log
+}
+
+func TestProcessDomainsURLsOnly(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*urlsOnly = true
+	defer func() { *urlsOnly = false }()
+
+	pool, _ := newFakeCSE(t, 5)
+	before := time.Now()
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+	if searches["example.com"].Count != 5 {
+		t.Fatalf("count = %d, want 5", searches["example.com"].Count)
+	}
+
+	found := collectedResults()
+	if len(found) != 5 {
+		t.Fatalf("collected %d results, want 5", len(found))
+	}
+	for _, result := range found {
+		if result.URL == "" || result.Title != "" || len(result.Subdomains) != 0 {
+			t.Errorf("-urls result carries more than the URL: %+v", result)
+		}
+		if result.CollectedAt.Before(before) || result.CollectedAt.After(time.Now()) {
+			t.Errorf("CollectedAt = %v, want the time the result was parsed", result.CollectedAt)
+		}
+	}
+}
+
+func TestNewListCallParameters(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*includeOmitted, *geoArg, *fileTypeParam, *c2coff = true, "de", "pdf", true
+	activeLocale = searchLocale{gl: "de", hl: "de", lr: "lang_de"}
+	defer func() {
+		*includeOmitted, *geoArg, *fileTypeParam, *c2coff, activeLocale = false, "", "", false, searchLocale{}
+	}()
+
+	pool, log := newFakeCSE(t, 3)
+	processDomains(context.Background(), []Target{{Domain: "example.com", Query: "inurl:admin"}}, pool)
+
+	requests := log.all()
+	if len(requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(requests))
+	}
+	want := map[string]string{"q": "site:example.com inurl:admin", "filter": "0", "gl": "de", "fileType": "pdf", "hl": "de", "lr": "lang_de", "c2coff": "1", "cx": "cx"}
+	for key, value := range want {
+		if got := requests[0].Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestSiteSearchParameters(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*siteSearch, *siteFilter, *linksTo = "docs.example.com", "e", "https://example.org/"
+	defer func() { *siteSearch, *siteFilter, *linksTo = "", "", "" }()
+
+	pool, log := newFakeCSE(t, 3)
+	processDomains(context.Background(), []Target{{Domain: "example.com", Query: "ext:pdf"}}, pool)
+
+	request := log.all()[0]
+	want := map[string]string{"q": "site:example.com ext:pdf", "siteSearch": "docs.example.com", "siteSearchFilter": "e", "linkSite": "https://example.org/"}
+	for key, value := range want {
+		if got := request.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	*siteSearch, *siteFilter, *linksTo = "", "", ""
+	cache := &responseCache{dir: t.TempDir()}
+	unscoped := cache.path("q", 1, 10)
+	*siteSearch = "docs.example.com"
+	if cache.path("q", 1, 10) == unscoped {
+		t.Error("-site-search did not change the cache key")
+	}
+	*siteSearch, *linksTo = "", "https://example.org/"
+	if cache.path("q", 1, 10) == unscoped {
+		t.Error("-links-to did not change the cache key")
+	}
+}
+
+func TestProcessDomainsHead(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*headLimit = 12
+	defer func() { *headLimit = 0 }()
+
+	pool, log := newFakeCSE(t, 30)
+	var targets []Target
+	for i := 0; i < 5; i++ {
+		targets = append(targets, Target{Domain: fmt.Sprintf("example%d.com", i)})
+	}
+	processDomains(context.Background(), targets, pool)
+
+	if found := collectedResults(); len(found) != 12 {
+		t.Errorf("collected %d results, want 12", len(found))
+	}
+	for _, request := range log.all() {
+		if request.Get("start") != "1" {
+			t.Errorf("fetched page at start=%s, want only first pages", request.Get("start"))
+		}
+	}
+	if _, failed, _ := progress.counts(); failed != 0 {
+		t.Errorf("%d domains failed, want searches stopped by -head to be skipped", failed)
+	}
+}
+
+func TestProcessDomainsKeepsPagesBeforeFailure(t *testing.T) {
+	setupLogger()
+	resetResults()
+
+	pool, _ := newFailingCSE(t, 50, 11)
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}, {Domain: "example.org"}}, pool)
+
+	for _, domain := range []string{"example.com", "example.org"} {
+		search, ok := searches[domain]
+		if !ok {
+			t.Fatalf("%s dropped after a failed second page", domain)
+		}
+		if !search.Partial || search.Count != 10 {
+			t.Errorf("%s = %+v, want 10 partial results", domain, search)
+		}
+	}
+	if len(searches["example.com"].Subdomains) == 0 {
+		t.Error("subdomains from the first page were discarded")
+	}
+	if found := collectedResults(); len(found) != 20 {
+		t.Errorf("collected %d results, want 20", len(found))
+	}
+	if _, failed, _ := progress.counts(); failed != 0 {
+		t.Errorf("%d domains failed, want 0", failed)
+	}
+}
+
+func TestExtraParams(t *testing.T) {
+	setupLogger()
+	resetResults()
+	for _, arg := range []string{"hq=intitle:index", "siteSearch=a.example.com", "siteSearch=b.example.com"} {
+		if err := extraParams.Set(arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for key := range extraParams {
+			delete(extraParams, key)
+		}
+	}()
+
+	pool, log := newFakeCSE(t, 1)
+	processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	request := log.all()[0]
+	if request.Get("hq") != "intitle:index" {
+		t.Errorf("hq = %q", request.Get("hq"))
+	}
+	if got := request["siteSearch"]; len(got) != 2 || got[0] != "a.example.com" || got[1] != "b.example.com" {
+		t.Errorf("siteSearch = %v, want both values", got)
+	}
+	if request.Get("cx") != "cx" {
+		t.Errorf("cx = %q, want the engine's own ID", request.Get("cx"))
+	}
+}
+
+func TestParamFlagsValidation(t *testing.T) {
+	for _, arg := range []string{"cx=other", "key=leak", "start=50", "no-equals", "bad name=x", "hq=a\r\nHost: evil"} {
+		if err := (paramFlags{}).Set(arg); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", arg)
+		}
+	}
+}
+
+func TestSearchResultQueries(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, _ := newFakeCSE(t, 2)
+	searches := processDomains(context.Background(), []Target{
+		{Domain: "example.com", Query: "inurl:login"},
+		{Domain: "example.com", Query: "filetype:pdf"},
+	}, pool)
+
+	want := "site:example.com filetype:pdf,site:example.com inurl:login"
+	if got := strings.Join(searches["example.com"].Queries, ","); got != want {
+		t.Errorf("queries = %s, want %s", got, want)
+	}
+
+	*detailedJSON = true
+	defer func() { *detailedJSON = false }()
+	out := outputTarget{format: "json", path: filepath.Join(t.TempDir(), "out.json")}
+	if err := outputResultsJSON(collectedResults(), searches, out); err != nil {
+		t.Fatal(err)
+	}
+	var grouped []SearchResult
+	data, _ := os.ReadFile(out.path)
+	if err := json.Unmarshal(data, &grouped); err != nil {
+		t.Fatal(err)
+	}
+	if len(grouped) != 1 || strings.Join(grouped[0].Queries, ",") != want {
+		t.Errorf("detailed JSON = %s", data)
+	}
+}
+
+func TestDomainSummaryLines(t *testing.T) {
+	setupLogger()
+	resetResults()
+	var lines strings.Builder
+	domainLines = &lines
+	defer func() { domainLines = nil }()
+
+	pool, _ := newFakeCSE(t, 2)
+	processDomains(context.Background(), []Target{
+		{Domain: "example.com", Query: "inurl:login"},
+		{Domain: "example.com", Query: "filetype:pdf"},
+	}, pool)
+	failing, _ := newFailingCSE(t, 2, 1)
+	processDomains(context.Background(), []Target{{Domain: "example.org"}}, failing)
+
+	want := "DOMAIN example.com results=4 subs=2 truncated=false errs=0\n" +
+		"DOMAIN example.org results=0 subs=0 truncated=false errs=1\n"
+	if lines.String() != want {
+		t.Errorf("lines =\n%s\nwant\n%s", lines.String(), want)
+	}
+	if kinds := progress.failureKinds(); len(kinds) != 1 || kinds[ErrorBadRequest] != 1 {
+		t.Errorf("failure kinds = %v, want bad_request=1", kinds)
+	}
+}
+
+func TestTotalPerDomain(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*totalPerDomain = 15
+	defer func() { *totalPerDomain = apiResultCeiling }()
+
+	pool, log := newFakeCSE(t, 100)
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	var nums []string
+	for _, request := range log.all() {
+		nums = append(nums, request.Get("start")+"+"+request.Get("num"))
+	}
+	if got := strings.Join(nums, ","); got != "1+10,11+5" {
+		t.Errorf("pages = %s, want 1+10,11+5", got)
+	}
+	if searches["example.com"].Count != 15 {
+		t.Errorf("count = %d, want 15", searches["example.com"].Count)
+	}
+}
+
+func TestPagesAndPerPage(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*pagesArg, *perPageArg = 3, 4
+	pageDelay = 0
+	defer func() { *pagesArg, *perPageArg, pageDelay = 10, 10, time.Second }()
+
+	pool, log := newFakeCSE(t, 100)
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	var nums []string
+	for _, request := range log.all() {
+		nums = append(nums, request.Get("start")+"+"+request.Get("num"))
+	}
+	if got := strings.Join(nums, ","); got != "1+4,5+4,9+4" {
+		t.Errorf("pages = %s, want 1+4,5+4,9+4", got)
+	}
+	if searches["example.com"].Count != 12 {
+		t.Errorf("count = %d, want 12", searches["example.com"].Count)
+	}
+}
+
+func TestLastPageStaysWithinAPIMaximum(t *testing.T) {
+	setupLogger()
+	resetResults()
+	// Beyond the validated range, to check the loop itself stops at 100.
+	*totalPerDomain, *pagesArg = 150, 30
+	pageDelay = 0
+	defer func() { *totalPerDomain, *pagesArg, pageDelay = apiResultCeiling, 10, time.Second }()
+
+	pool, log := newFakeCSE(t, 500)
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	requests := log.all()
+	for _, request := range requests {
+		start, _ := strconv.Atoi(request.Get("start"))
+		num, _ := strconv.Atoi(request.Get("num"))
+		if start+num > 101 {
+			t.Errorf("requested start=%d num=%d, which the API rejects", start, num)
+		}
+	}
+	if last := requests[len(requests)-1]; len(requests) != 10 || last.Get("start") != "91" || last.Get("num") != "10" {
+		t.Errorf("%d requests ending at start=%s num=%s, want 10 ending at 91+10", len(requests), last.Get("start"), last.Get("num"))
+	}
+	if search := searches["example.com"]; search.Error != "" || search.Count != 100 || !search.Truncated {
+		t.Errorf("search = count %d, error %q, truncated %v; want 100 truncated results", search.Count, search.Error, search.Truncated)
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct{ start, total, want int64 }{
+		{1, 100, 10},
+		{91, 100, 10},
+		{11, 15, 5},
+		{95, 200, 6},
+		{101, 200, 0},
+	}
+	for _, tt := range tests {
+		if got := pageSize(tt.start, tt.total, 10); got != tt.want {
+			t.Errorf("pageSize(%d, %d) = %d, want %d", tt.start, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestMaxEmptyPages(t *testing.T) {
+	setupLogger()
+	resetResults()
+	*maxEmptyPages = 2
+	pageDelay = 0
+	urlDenylist = &denylist{exact: make(map[string]bool)}
+	for i := 11; i <= 100; i++ {
+		urlDenylist.exact[fmt.Sprintf("https://s%d.example.com/page%d", i%3, i)] = true
+	}
+	defer func() { *maxEmptyPages, pageDelay, urlDenylist = 0, time.Second, nil }()
+
+	pool, log := newFakeCSE(t, 100)
+	processDomains(context.Background(), []Target{{Domain: "example.com"}}, pool)
+
+	// Page 1 keeps its results; pages 2 and 3 are entirely on the denylist.
+	if requests := len(log.all()); requests != 3 {
+		t.Errorf("%d requests, want 3: stop after 2 pages with nothing kept", requests)
+	}
+	if found := len(collectedResults()); found != 10 {
+		t.Errorf("collected %d results, want the 10 of page 1", found)
+	}
+}
+
+func TestFullResultsOutput(t *testing.T) {
+	setupLogger()
+	resetResults()
+	pool, _ := newFakeCSE(t, 2)
+	searches := processDomains(context.Background(), []Target{{Domain: "example.com", Query: "ext:pdf"}}, pool)
+	found := collectedResults()
+
+	dir := t.TempDir()
+	for _, format := range []string{"json", "csv", "txt"} {
+		out := outputTarget{format: format, path: filepath.Join(dir, "out."+format)}
+		if err := outputResults(found, searches, out); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		data, err := os.ReadFile(out.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every hit keeps its title, URL and snippet, not just its host.
+		for _, want := range []string{"Result 1", "https://s1.example.com/page1", "Result 2", "https://s2.example.com/page2", "snippet for site:example.com ext:pdf"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s output lacks %q:\n%s", format, want, data)
+			}
+		}
+	}
+}
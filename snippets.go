@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// snippetTexts returns the distinct snippets of results in the order they
+// were found, each on a single line, for -snippet-only.
+func snippetTexts(results []Result) []string {
+	seen := make(map[string]bool, len(results))
+	var snippets []string
+	for _, result := range results {
+		snippet := strings.Join(strings.Fields(result.Snippet), " ")
+		if snippet != "" && !seen[snippet] {
+			seen[snippet] = true
+			snippets = append(snippets, snippet)
+		}
+	}
+	return snippets
+}
+
+func outputSnippets(results []Result, out outputTarget) error {
+	snippets := snippetTexts(results)
+	var output strings.Builder
+	switch out.format {
+	case "json":
+		if snippets == nil {
+			snippets = []string{}
+		}
+		data, err := marshalOutput(snippets)
+		if err != nil {
+			return err
+		}
+		output.Write(data)
+		output.WriteString("\n")
+	case "csv":
+		writer := csv.NewWriter(&output)
+		writer.Write([]string{"Snippet"})
+		for _, snippet := range snippets {
+			writer.Write([]string{snippet})
+		}
+		writer.Flush()
+	default:
+		for _, snippet := range snippets {
+			output.WriteString(snippet + "\n")
+		}
+	}
+
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
+
+// matchSnippets keeps the results whose snippet matches re, for -match.
+func matchSnippets(results []Result, re *regexp.Regexp) []Result {
+	var kept []Result
+	for _, result := range results {
+		if re.MatchString(result.Snippet) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSnippetTexts(t *testing.T) {
+	results := []Result{
+		{URL: "https://example.com/a", Snippet: "api_key = AIzaSyA...\nfound in config"},
+		{URL: "https://example.com/b", Snippet: "api_key = AIzaSyA... found in  config"},
+		{URL: "https://example.com/c", Snippet: "Internal host db01.corp.example.com"},
+		{URL: "https://example.com/d"},
+	}
+	want := []string{"api_key = AIzaSyA... found in config", "Internal host db01.corp.example.com"}
+	if got := snippetTexts(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("snippetTexts = %q, want %q", got, want)
+	}
+
+	matched := matchSnippets(results, regexp.MustCompile(`AIza[0-9A-Za-z_-]*`))
+	if len(matched) != 2 || matched[1].URL != "https://example.com/b" {
+		t.Errorf("matchSnippets kept %+v, want the two api_key results", matched)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// splitByFileType writes the distinct URLs of each file type to
+// <dir>/<type>.txt for -split-by-filetype, e.g. pdf.txt and xlsx.txt.
+// File types are short alphanumeric extensions, so they are safe names.
+func splitByFileType(dir string, results []Result) error {
+	if err := os.MkdirAll(dir, outputDirMode()); err != nil {
+		return err
+	}
+	for _, group := range groupByFileType(results) {
+		var urls strings.Builder
+		for _, link := range urlList(group.Results) {
+			urls.WriteString(link + "\n")
+		}
+		if err := writeOutput(filepath.Join(dir, group.FileType+".txt"), []byte(urls.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
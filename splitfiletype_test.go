@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitByFileType(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "types")
+	results := []Result{
+		{URL: "https://example.com/b.pdf", FileType: "pdf"},
+		{URL: "https://example.com/q.xlsx", FileType: "xlsx"},
+		{URL: "https://example.com/a.pdf", FileType: "pdf"},
+		{URL: "https://example.org/a.pdf", FileType: "pdf"},
+		{URL: "https://example.com/a.pdf", FileType: "pdf"},
+	}
+	if err := splitByFileType(dir, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"pdf.txt":  "https://example.com/a.pdf\nhttps://example.com/b.pdf\nhttps://example.org/a.pdf\n",
+		"xlsx.txt": "https://example.com/q.xlsx\n",
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(want) {
+		t.Errorf("wrote %d files, want %d", len(entries), len(want))
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var unsafeFilenameRe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// domainFilename turns a domain into a safe file name, so input such as
+// "../etc" or "a/b" cannot escape the output directory.
+func domainFilename(domain string) string {
+	name := unsafeFilenameRe.ReplaceAllString(strings.ToLower(domain), "_")
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		name = "_"
+	}
+	return name
+}
+
+type domainStream struct {
+	file *os.File
+	w    *bufio.Writer
+}
+
+// domainStreams appends each result to <dir>/<domain>.jsonl as it is found
+// and closes a domain's file once all of its searches are done.
+type domainStreams struct {
+	mu    sync.Mutex
+	dir   string
+	files map[string]*domainStream
+}
+
+// jsonlStreams is set when -output-jsonl-per-domain is given.
+var jsonlStreams *domainStreams
+
+func newDomainStreams(dir string) (*domainStreams, error) {
+	if err := os.MkdirAll(dir, outputDirMode()); err != nil {
+		return nil, err
+	}
+	return &domainStreams{dir: dir, files: make(map[string]*domainStream)}, nil
+}
+
+func (s *domainStreams) write(result Result) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream, ok := s.files[result.Domain]
+	if !ok {
+		path := filepath.Join(s.dir, domainFilename(result.Domain)+".jsonl")
+		file, err := openOutput(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+		if err != nil {
+			logger.Error("Failed to open %s: %v", path, err)
+			return
+		}
+		stream = &domainStream{file: file, w: bufio.NewWriter(file)}
+		s.files[result.Domain] = stream
+	}
+	if err := json.NewEncoder(stream.w).Encode(result); err != nil {
+		logger.Error("Failed to write result for domain %s: %v", result.Domain, err)
+		return
+	}
+	// Flush per result so a crash loses at most the line being written.
+	if err := stream.w.Flush(); err != nil {
+		logger.Error("Failed to write result for domain %s: %v", result.Domain, err)
+	}
+}
+
+// close flushes and closes the file of a finished domain.
+func (s *domainStreams) close(domain string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream, ok := s.files[domain]
+	if !ok {
+		return
+	}
+	delete(s.files, domain)
+	if err := stream.w.Flush(); err != nil {
+		logger.Error("Failed to write results for domain %s: %v", domain, err)
+	}
+	if err := stream.file.Close(); err != nil {
+		logger.Error("Failed to close results for domain %s: %v", domain, err)
+	}
+}
+
+// closeAll closes every file still open, e.g. after a timeout.
+func (s *domainStreams) closeAll() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	domains := make([]string, 0, len(s.files))
+	for domain := range s.files {
+		domains = append(domains, domain)
+	}
+	s.mu.Unlock()
+	for _, domain := range domains {
+		s.close(domain)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDomainFilename(t *testing.T) {
+	tests := map[string]string{
+		"Example.COM":      "example.com",
+		"../etc/passwd":    "_etc_passwd",
+		"a b/c":            "a_b_c",
+		"..":               "_",
+		"xn--bcher-kva.de": "xn--bcher-kva.de",
+	}
+	for domain, want := range tests {
+		if got := domainFilename(domain); got != want {
+			t.Errorf("domainFilename(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestOutputJSONLPerDomain(t *testing.T) {
+	setupLogger()
+	resetResults()
+	dir := t.TempDir()
+	streams, err := newDomainStreams(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonlStreams = streams
+	defer func() { jsonlStreams = nil }()
+
+	pool, _ := newFakeCSE(t, 3)
+	processDomains(context.Background(), []Target{
+		{Domain: "example.com", Query: "inurl:login"},
+		{Domain: "example.com", Query: "filetype:pdf"},
+		{Domain: "example.org"},
+	}, pool)
+
+	if len(streams.files) != 0 {
+		t.Errorf("%d files left open", len(streams.files))
+	}
+	for domain, want := range map[string]int{"example.com": 6, "example.org": 3} {
+		file, err := os.Open(filepath.Join(dir, domain+".jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := 0
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var result Result
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil || result.Domain != domain {
+				t.Errorf("%s: bad line %s", domain, scanner.Text())
+			}
+			lines++
+		}
+		file.Close()
+		if lines != want {
+			t.Errorf("%s: %d lines, want %d", domain, lines, want)
+		}
+	}
+}
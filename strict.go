@@ -0,0 +1,18 @@
+package main
+
+import "os"
+
+// exitIfStrict fails the run under -strict when anything was warned about
+// or any domain failed, for CI jobs that must not pass on a degraded run.
+func exitIfStrict() {
+	if !*strict {
+		return
+	}
+	warnings := logger.warnings.Load()
+	_, failed, _ := progress.counts()
+	if warnings == 0 && failed == 0 {
+		return
+	}
+	logger.Error("-strict: %d warnings and %d failed domains, exiting with an error", warnings, failed)
+	os.Exit(1)
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestWarningsCountedWhenHidden(t *testing.T) {
+	*verbosity = int(ERROR)
+	defer func() { *verbosity = 1 }()
+	setupLogger()
+
+	logger.Warn("hidden at this level")
+	logger.Info("not a warning")
+	logger.Warn("hidden too")
+	if got := logger.warnings.Load(); got != 2 {
+		t.Errorf("counted %d warnings, want 2", got)
+	}
+}
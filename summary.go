@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// RunSummary is the machine-readable record of a run written to -summary-fd.
+type RunSummary struct {
+	Version         string              `json:"version"`
+	StartedAt       time.Time           `json:"started_at"`
+	DurationSeconds float64             `json:"duration_seconds"`
+	Domains         int                 `json:"domains"`
+	Failed          int                 `json:"failed"`
+	Failures        map[ErrorKind]int   `json:"failures,omitempty"`
+	Results         int                 `json:"results"`
+	Subdomains      int                 `json:"subdomains"`
+	Flagged         []string            `json:"flagged,omitempty"`
+	Output          string              `json:"output,omitempty"`
+	OutputError     string              `json:"output_error,omitempty"`
+	Destinations    []DestinationStatus `json:"destinations,omitempty"`
+	Keys            []KeyUsage          `json:"keys,omitempty"`
+	// EstimatedSpendUSD is what the run's queries beyond the free tier cost.
+	EstimatedSpendUSD float64 `json:"estimated_spend_usd"`
+	// AvgPageLatencyMS is the mean API page fetch time with -verbose-timing.
+	AvgPageLatencyMS int64 `json:"avg_page_latency_ms,omitempty"`
+}
+
+func buildSummary(startTime time.Time, results map[string]SearchResult, outputErr error) RunSummary {
+	completed, failed, resultCount := progress.counts()
+	summary := RunSummary{
+		Version:         VERSION,
+		StartedAt:       startTime,
+		DurationSeconds: time.Since(startTime).Seconds(),
+		Domains:         completed,
+		Failed:          failed,
+		Failures:        progress.failureKinds(),
+		Results:         resultCount,
+		Output:          *outputArg,
+	}
+	if *verboseTiming {
+		summary.AvgPageLatencyMS = pageLatency.average().Milliseconds()
+	}
+	for _, result := range sortedSearchResults(results) {
+		summary.Subdomains += len(result.Subdomains)
+		if result.Flagged {
+			summary.Flagged = append(summary.Flagged, result.Domain)
+		}
+	}
+	if outputErr != nil {
+		summary.OutputError = outputErr.Error()
+	}
+	return summary
+}
+
+// writeSummary writes the summary as a single JSON line to an already open
+// file descriptor inherited from the parent, e.g. `3>summary.json`.
+func writeSummary(fd int, summary RunSummary) error {
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if file == nil {
+		return fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	return json.NewEncoder(file).Encode(summary)
+}
+
+// domainLines receives a -domain-summary-fd line for each domain as soon as
+// all of its searches finish. It is nil when the option is off.
+var domainLines io.Writer
+
+// formatDomainLine renders one greppable line per domain for shell
+// pipelines, e.g. `DOMAIN example.com results=23 subs=5 truncated=false errs=0`.
+func formatDomainLine(result SearchResult, errs int) string {
+	return fmt.Sprintf("DOMAIN %s results=%d subs=%d truncated=%t errs=%d",
+		result.Domain, result.Count, len(result.Subdomains), result.Truncated, errs)
+}
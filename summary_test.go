@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteSummary(t *testing.T) {
+	setupLogger()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	progress.start([]Target{{Domain: "example.com"}, {Domain: "example.org"}})
+	progress.done(SearchResult{Domain: "example.com"}, 3)
+	progress.done(SearchResult{Domain: "example.org", Error: "Search failed", ErrorKind: ErrorQuota}, 0)
+	results := map[string]SearchResult{"example.com": {Subdomains: []string{"a.example.com", "b.example.com"}}}
+
+	summary := buildSummary(time.Now(), results, errors.New("disk full"))
+	if err := writeSummary(int(w.Fd()), summary); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	var got RunSummary
+	if err := json.NewDecoder(r).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Domains != 2 || got.Failed != 1 || got.Results != 3 || got.Subdomains != 2 || got.OutputError != "disk full" ||
+		len(got.Failures) != 1 || got.Failures[ErrorQuota] != 1 {
+		t.Errorf("summary = %+v", got)
+	}
+}
+
+func TestFlagDomains(t *testing.T) {
+	setupLogger()
+	results := map[string]SearchResult{
+		"a.com": {Domain: "a.com", Count: 5},
+		"b.com": {Domain: "b.com", Count: 40},
+		"c.com": {Domain: "c.com", Count: 11},
+		"d.com": {Domain: "d.com", Count: 10},
+	}
+
+	flagged := flagDomains(results, 10)
+	if len(flagged) != 2 || flagged[0].Domain != "b.com" || flagged[1].Domain != "c.com" {
+		t.Fatalf("flagged = %+v, want b.com then c.com", flagged)
+	}
+	if !results["b.com"].Flagged || results["d.com"].Flagged {
+		t.Errorf("flags not stored on results: %+v", results)
+	}
+
+	summary := buildSummary(time.Now(), results, nil)
+	if len(summary.Flagged) != 2 || summary.Flagged[0] != "b.com" || summary.Flagged[1] != "c.com" {
+		t.Errorf("summary flagged = %v", summary.Flagged)
+	}
+}
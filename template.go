@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// outputTemplate is the parsed -template-file used by -format template. It
+// is nil without the flag.
+var outputTemplate *template.Template
+
+// TemplateData is what -format template renders. Domains holds one entry per
+// searched domain, sorted by name, with its subdomains; Results holds every
+// result and is empty with -subs.
+type TemplateData struct {
+	Domains []SearchResult
+	Results []Result
+}
+
+// loadOutputTemplate parses a -template-file.
+func loadOutputTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("output").Funcs(templateFuncs).Parse(string(data))
+}
+
+func outputTemplated(data TemplateData, out outputTarget) error {
+	var output bytes.Buffer
+	if err := outputTemplate.Execute(&output, data); err != nil {
+		return err
+	}
+	if out.path != "" {
+		return writeOutputFile(out.path, output.Bytes())
+	}
+	fmt.Print(output.String())
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputTemplated(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "out.tmpl")
+	os.WriteFile(tmpl, []byte("{{range .Domains}}# {{.Domain}} ({{.Count}})\n{{end}}{{range .Results}}- {{.URL}} {{json .Title}}\n{{end}}"), 0644)
+
+	var err error
+	if outputTemplate, err = loadOutputTemplate(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { outputTemplate = nil }()
+	out := outputTarget{format: "template", path: filepath.Join(dir, "out.md")}
+
+	searches := map[string]SearchResult{
+		"b.com": {Domain: "b.com", Count: 1},
+		"a.com": {Domain: "a.com", Count: 1},
+	}
+	found := []Result{
+		{Domain: "a.com", URL: "https://a.com/x", Title: `Say "hi"`},
+		{Domain: "b.com", URL: "https://b.com/y", Title: "Y"},
+	}
+	if err := outputTemplated(TemplateData{Domains: sortedSearchResults(searches), Results: found}, out); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(out.path)
+	want := "# a.com (1)\n# b.com (1)\n- https://a.com/x \"Say \\\"hi\\\"\"\n- https://b.com/y \"Y\"\n"
+	if string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	os.WriteFile(tmpl, []byte("{{range .Results}"), 0644)
+	if _, err := loadOutputTemplate(tmpl); err == nil {
+		t.Error("loadOutputTemplate accepted a broken template")
+	}
+}
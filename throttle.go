@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// cooldownUntil is when the global -throttle-on-429 cooldown ends, in Unix
+// nanoseconds. Zero or a past time means no cooldown.
+var cooldownUntil atomic.Int64
+
+// startCooldown pauses every worker for d from now. A cooldown that already
+// runs longer is kept.
+func startCooldown(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := cooldownUntil.Load()
+		if current >= until {
+			return
+		}
+		if cooldownUntil.CompareAndSwap(current, until) {
+			if current < time.Now().UnixNano() {
+				logger.Warn("Rate limited, pausing all workers for %v", d)
+			}
+			return
+		}
+	}
+}
+
+// waitForCooldown blocks until the global cooldown is over.
+func waitForCooldown(ctx context.Context) error {
+	for {
+		wait := time.Until(time.Unix(0, cooldownUntil.Load()))
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
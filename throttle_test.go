@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCooldown(t *testing.T) {
+	setupLogger()
+	defer cooldownUntil.Store(0)
+
+	if err := waitForCooldown(context.Background()); err != nil {
+		t.Fatalf("no cooldown: %v", err)
+	}
+
+	startCooldown(time.Hour)
+	startCooldown(time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := waitForCooldown(ctx); err == nil {
+		t.Fatal("a shorter cooldown cut the running one short")
+	}
+
+	cooldownUntil.Store(0)
+	startCooldown(20 * time.Millisecond)
+	start := time.Now()
+	if err := waitForCooldown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 15*time.Millisecond {
+		t.Errorf("waited %v, want about 20ms", waited)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyWindow is how many recent page fetches the -verbose-timing rolling
+// average covers.
+const latencyWindow = 20
+
+// latencyStats tracks how long page fetches take, for -verbose-timing.
+type latencyStats struct {
+	mu     sync.Mutex
+	recent []time.Duration
+	next   int
+	count  int
+	total  time.Duration
+}
+
+var pageLatency = &latencyStats{}
+
+// observe records one fetch and returns the average of the most recent
+// fetches.
+func (s *latencyStats) observe(d time.Duration) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.total += d
+	if len(s.recent) < latencyWindow {
+		s.recent = append(s.recent, d)
+	} else {
+		s.recent[s.next] = d
+		s.next = (s.next + 1) % latencyWindow
+	}
+	var sum time.Duration
+	for _, r := range s.recent {
+		sum += r
+	}
+	return sum / time.Duration(len(s.recent))
+}
+
+// average is the mean over every fetch observed, or 0 before the first.
+func (s *latencyStats) average() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return 0
+	}
+	return s.total / time.Duration(s.count)
+}
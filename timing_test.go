@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyStats(t *testing.T) {
+	stats := &latencyStats{}
+	if avg := stats.average(); avg != 0 {
+		t.Errorf("average before any fetch = %v", avg)
+	}
+	if avg := stats.observe(100 * time.Millisecond); avg != 100*time.Millisecond {
+		t.Errorf("rolling average after one fetch = %v", avg)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		stats.observe(300 * time.Millisecond)
+	}
+	// The first fetch has rolled out of the window but still counts overall.
+	if avg := stats.observe(300 * time.Millisecond); avg != 300*time.Millisecond {
+		t.Errorf("rolling average = %v, want 300ms", avg)
+	}
+	want := (100*time.Millisecond + 21*300*time.Millisecond) / 22
+	if avg := stats.average(); avg != want {
+		t.Errorf("overall average = %v, want %v", avg, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// customTransport reports whether API calls need a client of our own rather
+// than the one the API library builds, i.e. when a proxy or TLS option is set.
+func customTransport() bool {
+	return *proxyArg != "" || *caCertFile != "" || *insecureTLS
+}
+
+// newAPITransport builds the transport for API calls from -proxy,
+// -ca-cert and -insecure-skip-verify. Without -proxy, the usual
+// HTTPS_PROXY environment variables still apply.
+func newAPITransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if *proxyArg != "" {
+		proxyURL, err := url.Parse(*proxyArg)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", *proxyArg)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: *insecureTLS}
+	if *caCertFile != "" {
+		pem, err := os.ReadFile(*caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", *caCertFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// apiKeyTransport adds the API key to every request. The API library only
+// does this for clients it builds itself.
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("key", t.key)
+	req.URL.RawQuery = query.Encode()
+	return t.base.RoundTrip(req)
+}
+
+// apiKeyOption authenticates a service with apiKey, over the custom
+// transport when one is configured.
+func apiKeyOption(transport http.RoundTripper, apiKey string) option.ClientOption {
+	if transport == nil {
+		return option.WithAPIKey(apiKey)
+	}
+	return option.WithHTTPClient(&http.Client{Transport: &apiKeyTransport{key: apiKey, base: transport}})
+}
+
+// serviceAccountClient is an OAuth client for -auth serviceaccount whose
+// token and API requests both go through transport.
+func serviceAccountClient(ctx context.Context, transport http.RoundTripper) (*http.Client, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	var creds *google.Credentials
+	var err error
+	if *credentialsFile != "" {
+		var data []byte
+		if data, err = os.ReadFile(*credentialsFile); err != nil {
+			return nil, err
+		}
+		creds, err = google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, creds.TokenSource), nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPITransportTLS(t *testing.T) {
+	var gotKey string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.URL.Query().Get("key")
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	os.WriteFile(caFile, certPEM, 0644)
+	defer func() { *caCertFile, *insecureTLS = "", false }()
+
+	tests := []struct {
+		name     string
+		caCert   string
+		insecure bool
+		wantErr  bool
+	}{
+		{"strict by default", "", false, true},
+		{"custom CA", caFile, false, false},
+		{"skip verify", "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*caCertFile, *insecureTLS = tt.caCert, tt.insecure
+			transport, err := newAPITransport()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &apiKeyTransport{key: "secret", base: transport}}
+			gotKey = ""
+			resp, err := client.Get(server.URL + "/customsearch/v1?q=test")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+				if gotKey != "secret" {
+					t.Errorf("key = %q, want secret", gotKey)
+				}
+			}
+		})
+	}
+}
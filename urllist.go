@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// urlList returns the distinct result URLs, sorted, for -format urllist.
+func urlList(results []Result) []string {
+	seen := make(map[string]bool, len(results))
+	var urls []string
+	for _, result := range results {
+		if result.URL != "" && !seen[result.URL] {
+			seen[result.URL] = true
+			urls = append(urls, result.URL)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// outputURLList writes a crawler seed list: one URL per line, nothing else.
+func outputURLList(results []Result, out outputTarget) error {
+	var output strings.Builder
+	for _, link := range urlList(results) {
+		output.WriteString(link + "\n")
+	}
+	if out.path != "" {
+		return writeOutputFile(out.path, []byte(output.String()))
+	}
+	fmt.Print(output.String())
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestURLList(t *testing.T) {
+	results := []Result{
+		{Domain: "example.org", URL: "https://example.org/b"},
+		{Domain: "example.com", URL: "https://example.com/a"},
+		{Domain: "example.com", URL: "https://example.com/a"},
+		{Domain: "example.com"},
+	}
+	want := []string{"https://example.com/a", "https://example.org/b"}
+	if got := urlList(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("urlList = %v, want %v", got, want)
+	}
+}
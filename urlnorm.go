@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// normalizeURL rewrites a result link into a canonical form for
+// -normalize-urls: lowercase host, no default port and no fragment. With
+// stripSlash, trailing slashes are removed from the path too. Links that do
+// not parse are returned unchanged.
+func normalizeURL(link string, stripSlash bool) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" && port != defaultPorts[parsed.Scheme] {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	parsed.Host = host
+	parsed.Fragment, parsed.RawFragment = "", ""
+
+	if stripSlash {
+		parsed.Path = strings.TrimRight(parsed.Path, "/")
+		parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+	}
+	return parsed.String()
+}
+
+// resultLink is the URL a result is recorded under.
+func resultLink(link string) string {
+	if !*normalizeURLs {
+		return link
+	}
+	return normalizeURL(link, *stripSlash)
+}
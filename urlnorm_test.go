@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		link       string
+		stripSlash bool
+		want       string
+	}{
+		{"HTTPS://Example.COM:443/Path/?q=A#top", false, "https://example.com/Path/?q=A"},
+		{"http://Example.com:80/", false, "http://example.com/"},
+		{"http://example.com:8080/a", false, "http://example.com:8080/a"},
+		{"https://example.com:80/a", false, "https://example.com:80/a"},
+		{"https://example.com/docs/", true, "https://example.com/docs"},
+		{"https://example.com/", true, "https://example.com"},
+		{"https://example.com/a%2Fb/", true, "https://example.com/a%2Fb"},
+		{"https://[2001:DB8::1]:443/x", false, "https://[2001:db8::1]/x"},
+		{"https://[2001:db8::1]:8443/x", false, "https://[2001:db8::1]:8443/x"},
+		{"not a url", true, "not a url"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.link, tt.stripSlash); got != tt.want {
+			t.Errorf("normalizeURL(%q, %v) = %q, want %q", tt.link, tt.stripSlash, got, tt.want)
+		}
+	}
+}
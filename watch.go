@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// quotaLocation is where the Custom Search API's day ends: daily quotas
+// reset at midnight Pacific Time.
+var quotaLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("America/Los_Angeles"); err == nil {
+		return loc
+	}
+	return time.FixedZone("PST", -8*60*60)
+}()
+
+// nextQuotaReset returns the first midnight Pacific Time after t.
+func nextQuotaReset(t time.Time) time.Time {
+	local := t.In(quotaLocation)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, quotaLocation)
+}
+
+// nextWatchRun is when the run that started at start should be followed
+// by the next one. If the daily quota ran out, it waits for the reset.
+func nextWatchRun(start, now time.Time, interval time.Duration, quotaExhausted bool) time.Time {
+	next := start.Add(interval)
+	if quotaExhausted {
+		if reset := nextQuotaReset(now); reset.After(next) {
+			return reset
+		}
+	}
+	return next
+}
+
+// watch repeats the search until ctx is cancelled. Items already written by
+// an earlier run are filtered out by run.seen, so every run after the first
+// only outputs new findings.
+func watch(ctx context.Context, pool *KeyPool, targets []Target, run runOptions) {
+	if run.seen == nil {
+		run.seen = NewBloomFilter(*bloomSize, uint32(*bloomHashes))
+	}
+
+	for n := 1; ; n++ {
+		start := time.Now()
+		logger.Info("Watch run %d starting", n)
+		if err := runSearch(ctx, pool, targets, run, start); err != nil {
+			logger.Warn("Watch run %d could not write all output, continuing", n)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		quotaExhausted := pool.QuotaExhausted()
+		next := nextWatchRun(start, time.Now(), *watchInterval, quotaExhausted)
+		if quotaExhausted {
+			logger.Warn("Daily quota exhausted, next run at %s", next.Format(time.RFC3339))
+		} else {
+			logger.Info("Next run at %s", next.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(next)):
+			if quotaExhausted {
+				pool.ResetHealth()
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	logger.Info("Watch stopped")
+}
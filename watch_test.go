@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNextWatchRun(t *testing.T) {
+	// 15:00 UTC is 08:00 Pacific, so the quota resets 16 hours later.
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	now := start.Add(10 * time.Minute)
+	reset := time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		exceeded bool
+		want     time.Time
+	}{
+		{"interval", time.Hour, false, start.Add(time.Hour)},
+		{"quota waits for reset", time.Hour, true, reset},
+		{"interval past reset", 48 * time.Hour, true, start.Add(48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextWatchRun(start, now, tt.interval, tt.exceeded); !got.Equal(tt.want) {
+				t.Errorf("next = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchOutputsOnlyNewFindings(t *testing.T) {
+	setupLogger()
+	output := filepath.Join(t.TempDir(), "out.txt")
+	*watchInterval, *outputArg = 10*time.Millisecond, output
+	defer func() { *watchInterval, *outputArg = 0, "" }()
+
+	pool, log := newFakeCSE(t, 3)
+	seen := NewBloomFilter(1<<16, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, pool, []Target{{Domain: "example.com"}}, runOptions{seen: seen})
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(log.all()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after cancellation")
+	}
+
+	if len(log.all()) < 2 {
+		t.Fatal("watch did not run a second time")
+	}
+	if !seen.Test("url:https://s1.example.com/page1") {
+		t.Error("first run's results were not remembered")
+	}
+	if got, _ := os.ReadFile(output); len(got) != 0 {
+		t.Errorf("a later run repeated old findings:\n%s", got)
+	}
+}
+
+func TestWatchWebhookOnlyForNewFindings(t *testing.T) {
+	setupLogger()
+	var posts atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer hook.Close()
+	*watchInterval, *webhookURL = 10*time.Millisecond, hook.URL
+	defer func() { *watchInterval, *webhookURL = 0, "" }()
+
+	pool, log := newFakeCSE(t, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, pool, []Target{{Domain: "example.com"}}, runOptions{seen: NewBloomFilter(1<<16, 4)})
+		close(done)
+	}()
+
+	// A third request means the second run, with nothing new, has finished.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(log.all()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after cancellation")
+	}
+
+	if len(log.all()) < 3 {
+		t.Fatal("watch did not run a third time")
+	}
+	if got := posts.Load(); got != 1 {
+		t.Errorf("webhook fired %d times, want once for the first run only", got)
+	}
+}
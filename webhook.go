@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// WebhookData is what a run posts to -webhook, and the data a
+// -webhook-template is rendered with.
+type WebhookData struct {
+	Run     RunSummary     `json:"run"`
+	Domains []SearchResult `json:"domains"`
+	Results []Result       `json:"results,omitempty"`
+}
+
+// templateFuncs are available to -webhook-template and -template-file. The
+// json function marshals any value, e.g. {{json .Run}}, for embedding in a
+// JSON body.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// loadWebhookTemplate parses a -webhook-template file.
+func loadWebhookTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("webhook").Funcs(templateFuncs).Parse(string(data))
+}
+
+func renderWebhookBody(tmpl *template.Template, data WebhookData) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(data)
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func postWebhook(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWebhookTemplate(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "slack.tmpl")
+	os.WriteFile(path, []byte(`{"text": {{json (printf "%d domains, %d failed" .Run.Domains .Run.Failed)}}, "blocks": [{{range $i, $d := .Domains}}{{if $i}},{{end}}{"domain": {{json $d.Domain}}, "subs": {{len $d.Subdomains}}}{{end}}]}`), 0644)
+	tmpl, err := loadWebhookTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := renderWebhookBody(tmpl, WebhookData{
+		Run: RunSummary{Domains: 2, Failed: 1},
+		Domains: []SearchResult{
+			{Domain: "example.com", Subdomains: []string{"a.example.com", "b.example.com"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postWebhook(server.Client(), server.URL, body); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Domain string `json:"domain"`
+			Subs   int    `json:"subs"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(received, &payload); err != nil {
+		t.Fatalf("webhook body is not JSON: %v\n%s", err, received)
+	}
+	if payload.Text != "2 domains, 1 failed" || len(payload.Blocks) != 1 || payload.Blocks[0].Subs != 2 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestPostWebhookRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	body, err := renderWebhookBody(nil, WebhookData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postWebhook(server.Client(), server.URL, body); err == nil {
+		t.Error("expected an error for a 400 response")
+	}
+}
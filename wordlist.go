@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// wordlistCharsetRe builds the pattern a token must match from -wordlist-charset,
+// a regexp character class body such as "a-zA-Z0-9_-".
+func wordlistCharsetRe(charset string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^[" + charset + "]+$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid -wordlist-charset %q: %v", charset, err)
+	}
+	return re, nil
+}
+
+// extractWords returns the path segments and query parameter names of every
+// result URL that are at least minLen characters long and made only of
+// characters in the charset, deduplicated and sorted.
+func extractWords(results []Result, minLen int, charset *regexp.Regexp) []string {
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if len(token) >= minLen && charset.MatchString(token) {
+			seen[token] = true
+		}
+	}
+
+	for _, result := range results {
+		u, err := url.Parse(result.URL)
+		if err != nil {
+			continue
+		}
+		for _, segment := range strings.Split(u.Path, "/") {
+			add(segment)
+		}
+		for name := range u.Query() {
+			add(name)
+		}
+	}
+
+	words := make([]string, 0, len(seen))
+	for word := range seen {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+func writeWordlist(path string, words []string) error {
+	var output strings.Builder
+	for _, word := range words {
+		output.WriteString(word + "\n")
+	}
+	if err := writeOutput(path, []byte(output.String())); err != nil {
+		return err
+	}
+	logger.Debug("Wrote %d words to %s", len(words), path)
+	return nil
+}
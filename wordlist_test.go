@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractWords(t *testing.T) {
+	results := []Result{
+		{URL: "https://a.example.com/admin/login.php?user=x&id=1"},
+		{URL: "https://b.example.com/admin/backup%20old/?token=y"},
+		{URL: "https://c.example.com/"},
+	}
+
+	tests := []struct {
+		name    string
+		minLen  int
+		charset string
+		want    string
+	}{
+		{"defaults", 3, "a-zA-Z0-9_.-", "admin,login.php,token,user"},
+		{"short tokens", 1, "a-zA-Z0-9_.-", "admin,id,login.php,token,user"},
+		{"spaces allowed", 3, "a-z ", "admin,backup old,token,user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charset, err := wordlistCharsetRe(tt.charset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(extractWords(results, tt.minLen, charset), ",")
+			if got != tt.want {
+				t.Errorf("words = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := wordlistCharsetRe("z-a"); err == nil {
+		t.Error("expected an error for an invalid character class")
+	}
+}
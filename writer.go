@@ -0,0 +1,53 @@
+package main
+
+import "sync/atomic"
+
+// resultQueueSize is how many results the searches can hand over before
+// they wait for the writer.
+const resultQueueSize = 256
+
+// writeOp is one job for the result writer: a result to store, or with
+// finish set, the end of result.Domain's searches.
+type writeOp struct {
+	result Result
+	finish bool
+}
+
+var (
+	// resultQueue feeds the writer goroutine while processDomains runs.
+	resultQueue chan writeOp
+	// storedResults counts results accepted so far, for -head.
+	storedResults atomic.Int64
+)
+
+// startResultWriter starts the one goroutine that owns the collected
+// results and the streaming outputs. Searches hand their results over on
+// resultQueue instead of contending on a lock, and since a domain's finish
+// is queued behind its results, its -output-jsonl-per-domain file is only
+// closed once they are all written. stop waits for the queue to drain.
+func startResultWriter() (stop func()) {
+	queue := make(chan writeOp, resultQueueSize)
+	done := make(chan struct{})
+	resultQueue = queue
+	go func() {
+		defer close(done)
+		for op := range queue {
+			switch {
+			case op.finish:
+				jsonlStreams.close(op.result.Domain)
+				continue
+			case *lowMemory:
+				if lowMemorySink != nil {
+					lowMemorySink.write(op.result)
+				}
+			default:
+				results = append(results, op.result)
+			}
+			jsonlStreams.write(op.result)
+		}
+	}()
+	return func() {
+		close(queue)
+		<-done
+	}
+}
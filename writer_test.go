@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResultWriter(t *testing.T) {
+	setupLogger()
+	resetResults()
+	dir := t.TempDir()
+	streams, err := newDomainStreams(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonlStreams = streams
+	defer func() { jsonlStreams = nil }()
+
+	stop := startResultWriter()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				recordResult(Result{Domain: "example.com", URL: fmt.Sprintf("https://example.com/%d/%d", i, j)})
+			}
+		}(i)
+	}
+	wg.Wait()
+	// The finish is queued behind every result, so none are lost.
+	finishDomain(map[string]SearchResult{}, "example.com", map[string]int{}, map[string]int{})
+	stop()
+
+	if got := len(collectedResults()); got != 1000 {
+		t.Errorf("collected %d results, want 1000", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "example.com.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1000 {
+		t.Errorf("jsonl has %d lines, want 1000", lines)
+	}
+	if len(streams.files) != 0 {
+		t.Error("finishing the domain left its jsonl file open")
+	}
+}
+
+func TestResultWriterHead(t *testing.T) {
+	resetResults()
+	*headLimit = 7
+	defer func() { *headLimit = 0 }()
+
+	stop := startResultWriter()
+	kept := 0
+	for i := 0; i < 10; i++ {
+		if recordResult(Result{Domain: "example.com"}) {
+			kept++
+		}
+	}
+	stop()
+	if kept != 7 || len(collectedResults()) != 7 {
+		t.Errorf("kept %d, collected %d; want 7 with -head 7", kept, len(collectedResults()))
+	}
+}